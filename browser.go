@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"runtime"
+
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+)
+
+// maxOpen is the most files -open opens at once, so that a large -limit does
+// not flood the browser with tabs.
+const maxOpen = 10
+
+// openCommand returns the command opening link in the default browser on
+// the given operating system.
+func openCommand(goos, link string) []string {
+	switch goos {
+	case "darwin":
+		return []string{"open", link}
+	case "windows":
+		// The empty argument is the window title start would otherwise
+		// take from a quoted link
+		return []string{"cmd", "/c", "start", "", link}
+	}
+	return []string{"xdg-open", link}
+}
+
+// openURL opens link in the default browser.
+func openURL(link string) error {
+	command := openCommand(runtime.GOOS, link)
+	if out, err := exec.Command(command[0], command[1:]...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%s failed: %w %s", command[0], err, out)
+	}
+	return nil
+}
+
+// openFiles opens the GitHub page of every hit at its first matched line,
+// warning on warn about the ones that cannot be opened.
+func openFiles(hits *grepapp.Results, open func(string) error, warn io.Writer) {
+	for i := range hits.Hits {
+		link, err := clipText(&hits.Hits[i], "url")
+		if err == nil {
+			err = open(link)
+		}
+		if err != nil {
+			fmt.Fprintf(warn, "Warning: cannot open %s/%s: %s\n", hits.Hits[i].Repo, hits.Hits[i].Path, err)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package main_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	grepgithub "github.com/aviadhahami/grepgithub-go"
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenCommand(t *testing.T) {
+	link := "https://github.com/example/repo"
+	assert.Equal(t, []string{"xdg-open", link}, grepgithub.OpenCommand("linux", link))
+	assert.Equal(t, []string{"open", link}, grepgithub.OpenCommand("darwin", link))
+	assert.Equal(t, []string{"cmd", "/c", "start", "", link}, grepgithub.OpenCommand("windows", link))
+}
+
+func TestOpenFiles(t *testing.T) {
+	hits := &grepapp.Results{}
+	hits.AddHit("example/repo", "main.go", 3, "foo")
+	hits.AddHit("git.example.com/repo", "main.go", 1, "foo")
+	hits.AddHit("example/other", "main.go", 5, "foo")
+
+	var opened []string
+	var warn bytes.Buffer
+	grepgithub.OpenFiles(hits, func(link string) error {
+		opened = append(opened, link)
+		if len(opened) == 2 {
+			return errors.New("no browser")
+		}
+		return nil
+	}, &warn)
+	assert.Equal(t, []string{
+		"https://github.com/example/repo/blob/HEAD/main.go#L3",
+		"https://github.com/example/other/blob/HEAD/main.go#L5",
+	}, opened)
+	assert.Equal(t, "Warning: cannot open git.example.com/repo/main.go: git.example.com/repo is not a GitHub repository\n"+
+		"Warning: cannot open example/other/main.go: no browser\n", warn.String())
+}
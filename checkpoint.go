@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+)
+
+// checkpoint is what -checkpoint saves after every page, so that an
+// interrupted scan can resume from the page after the last one fetched.
+type checkpoint struct {
+	Query jsonQuery `json:"query"`
+	// PerPage is kept as pages of another size would not line up
+	PerPage int `json:"per_page,omitempty"`
+	// Term is the index of the -any term being searched, Page the last
+	// page fetched for it.
+	Term  int              `json:"term"`
+	Page  int              `json:"page"`
+	Count int              `json:"count"`
+	Hits  []grepapp.Result `json:"hits"`
+}
+
+// loadCheckpoint reads the checkpoint at path, or returns nil when there is
+// none. A checkpoint left by a different search is an error rather than
+// being overwritten.
+func loadCheckpoint(path string, args *Arguments) (*checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read checkpoint: %w", err)
+	}
+	var cp checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("Cannot read checkpoint %s: %w", path, err)
+	}
+	if !reflect.DeepEqual(cp.Query, queryOf(args)) || cp.PerPage != args.PerPage || cp.Term >= len(args.queries()) {
+		return nil, fmt.Errorf("Checkpoint %s is for a different search, remove it to start over", path)
+	}
+	return &cp, nil
+}
+
+// save writes cp to path, replacing the previous checkpoint at once so that
+// an interruption never leaves half of one.
+func (cp *checkpoint) save(path string) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	if err := writeFile(path, bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("Cannot save checkpoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package main_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	grepgithub "github.com/aviadhahami/grepgithub-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunResumesFromCheckpoint(t *testing.T) {
+	var requested []string
+	failing := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		requested = append(requested, page)
+		if page == "3" && failing {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprintf(w, `{"facets":{"count":3},"hits":{"hits":[{"repo":{"raw":"example/repo"},"path":{"raw":"%s.go"}}]}}`, page)
+	}))
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "scan.json")
+	flags := []string{"-q", "foo", "-delay", "0", "-retries", "0", "-strict", "-quiet", "-jsonl", "-base-url", server.URL, "-checkpoint", path}
+
+	args, err := grepgithub.ParseArguments(flags)
+	assert.NoError(t, err)
+	assert.Error(t, grepgithub.Run(context.Background(), args, &bytes.Buffer{}))
+	assert.FileExists(t, path)
+
+	failing = false
+	requested = nil
+	args, err = grepgithub.ParseArguments(flags)
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	assert.NoError(t, grepgithub.Run(context.Background(), args, &buf))
+	assert.Equal(t, []string{"3"}, requested)
+	assert.Equal(t, `{"repo":"example/repo","path":"1.go","lang":"Go","lines":[]}`+"\n"+
+		`{"repo":"example/repo","path":"2.go","lang":"Go","lines":[]}`+"\n"+
+		`{"repo":"example/repo","path":"3.go","lang":"Go","lines":[]}`+"\n", buf.String())
+	assert.NoFileExists(t, path)
+}
+
+func TestRunRejectsCheckpointOfAnotherSearch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scan.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"query":{"query":"bar"},"term":0,"page":2,"hits":[]}`), 0o644))
+
+	args, err := grepgithub.ParseArguments([]string{"-q", "foo", "-delay", "0", "-base-url", "http://127.0.0.1:0", "-checkpoint", path})
+	assert.NoError(t, err)
+	assert.EqualError(t, grepgithub.Run(context.Background(), args, &bytes.Buffer{}), "Checkpoint "+path+" is for a different search, remove it to start over")
+}
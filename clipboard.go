@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+)
+
+// clipboardCommands are the tools that can copy their input to the system
+// clipboard, tried in order.
+var clipboardCommands = [][]string{
+	{"pbcopy"},
+	{"wl-copy"},
+	{"xclip", "-selection", "clipboard"},
+	{"xsel", "--clipboard", "--input"},
+	{"clip.exe"},
+}
+
+var errNoClipboard = errors.New("No clipboard tool found, install one of pbcopy, wl-copy, xclip, xsel or clip.exe")
+
+// copyToClipboard copies text with the first clipboard tool on the PATH.
+func copyToClipboard(text string) error {
+	for _, command := range clipboardCommands {
+		path, err := exec.LookPath(command[0])
+		if err != nil {
+			continue
+		}
+		cmd := exec.Command(path, command[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("%s failed: %w %s", command[0], err, strings.TrimSpace(string(out)))
+		}
+		return nil
+	}
+	return errNoClipboard
+}
+
+// firstMatch returns the number of the first matched line of hit, skipping
+// context lines, or 0 if it has none.
+func firstMatch(hit *grepapp.Result) int {
+	for _, line := range hit.Lines {
+		if !line.Context {
+			return line.LineNumber
+		}
+	}
+	return 0
+}
+
+// clipText returns what -copy copies from hit: the GitHub permalink of its
+// first matched line for url, or the text of that line for line.
+func clipText(hit *grepapp.Result, what string) (string, error) {
+	if what == "line" {
+		for _, line := range hit.Lines {
+			if !line.Context {
+				return stripANSI(line.Text), nil
+			}
+		}
+		return "", fmt.Errorf("%s/%s has no matched line", hit.Repo, hit.Path)
+	}
+	link := grepapp.GithubURL(hit.Repo, hit.Path, firstMatch(hit))
+	if link == "" {
+		return "", fmt.Errorf("%s is not a GitHub repository", hit.Repo)
+	}
+	return link, nil
+}
+
+// copyResult copies what -copy asks for from hit and reports it on stderr.
+// Failing to copy does not fail the search, whose results are printed
+// already.
+func copyResult(hit *grepapp.Result, args *Arguments) {
+	text, err := clipText(hit, args.Copy)
+	if err == nil {
+		err = copyToClipboard(text)
+	}
+	switch {
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "Cannot copy to the clipboard: %s\n", err)
+	case !args.Quiet:
+		fmt.Fprintf(os.Stderr, "Copied %s\n", text)
+	}
+}
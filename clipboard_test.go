@@ -0,0 +1,44 @@
+package main_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	grepgithub "github.com/aviadhahami/grepgithub-go"
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCopyToClipboard(t *testing.T) {
+	dir := t.TempDir()
+	copied := filepath.Join(dir, "copied")
+	script := "#!/bin/sh\nIFS= read -r text\nprintf %s \"$text\" > " + copied + "\n"
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "xclip"), []byte(script), 0o755))
+	t.Setenv("PATH", dir)
+
+	assert.NoError(t, grepgithub.CopyToClipboard("https://github.com/example/repo"))
+	data, err := os.ReadFile(copied)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://github.com/example/repo", string(data))
+
+	t.Setenv("PATH", t.TempDir())
+	assert.ErrorIs(t, grepgithub.CopyToClipboard("x"), grepgithub.ErrNoClipboard)
+}
+
+func TestClipText(t *testing.T) {
+	hit := &grepapp.Result{Repo: "example/repo", Path: "main.go", Lines: []grepapp.Line{
+		{LineNumber: 2, Text: "before", Context: true},
+		{LineNumber: 3, Text: "foo(" + grepapp.C_MARK + "x" + grepapp.C_RST + ")"},
+	}}
+	text, err := grepgithub.ClipText(hit, "url")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://github.com/example/repo/blob/HEAD/main.go#L3", text)
+
+	text, err = grepgithub.ClipText(hit, "line")
+	assert.NoError(t, err)
+	assert.Equal(t, "foo(x)", text)
+
+	_, err = grepgithub.ClipText(&grepapp.Result{Repo: "git.example.com/repo", Path: "main.go"}, "url")
+	assert.EqualError(t, err, "git.example.com/repo is not a GitHub repository")
+}
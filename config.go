@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigPath returns ~/.config/grepgithub/config.yaml or its
+// platform equivalent.
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "grepgithub", "config.yaml")
+}
+
+// envFlags maps the environment variables that provide defaults to the flag
+// they stand for.
+var envFlags = []struct {
+	env, flag string
+}{
+	{"GREPGITHUB_LANG", "flang"},
+	{"GREPGITHUB_REPO", "frepo"},
+	{"GREPGITHUB_PATH", "fpath"},
+	{"GREPGITHUB_FORMAT", "format"},
+	{"GREPGITHUB_COLOR", "color"},
+	{"GREPGITHUB_DELAY", "delay"},
+	{"GREPGITHUB_MAX_PAGES", "max-pages"},
+	{"GREPGITHUB_RETRIES", "retries"},
+	{"GREPGITHUB_BASE_URL", "base-url"},
+	{"GITHUB_TOKEN", "github-token"},
+	{"GREPGITHUB_WEBHOOK_SECRET", "webhook-secret"},
+}
+
+// explicitFlags returns the names of the flags given on the command line.
+func explicitFlags(flags *flag.FlagSet) map[string]bool {
+	explicit := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+	return explicit
+}
+
+// applyEnv sets flags from their environment variables unless they were
+// given on the command line.
+func applyEnv(flags *flag.FlagSet, explicit map[string]bool) error {
+	for _, envFlag := range envFlags {
+		value, ok := os.LookupEnv(envFlag.env)
+		if !ok || explicit[envFlag.flag] {
+			continue
+		}
+		if err := flags.Set(envFlag.flag, value); err != nil {
+			return fmt.Errorf("Invalid value for %s: %w", envFlag.env, err)
+		}
+	}
+	return nil
+}
+
+// applyConfig sets the flags listed in the YAML file at path, keyed by flag
+// name, unless they were given on the command line. A missing file is only
+// an error when the path was given explicitly.
+func applyConfig(flags *flag.FlagSet, path string, explicit map[string]bool) error {
+	required := explicit["config"]
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !required {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Cannot read config file: %w", err)
+	}
+
+	var values map[string]any
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("Invalid config file %s: %w", path, err)
+	}
+
+	for name, value := range values {
+		if flags.Lookup(name) == nil || name == "config" {
+			return fmt.Errorf("Unknown option %q in config file %s", name, path)
+		}
+		if explicit[name] {
+			continue
+		}
+		if err := flags.Set(name, configValue(value)); err != nil {
+			return fmt.Errorf("Invalid value for %q in config file %s: %w", name, path, err)
+		}
+	}
+	return nil
+}
+
+// configValue formats a YAML value the way it would be passed as a flag.
+// Lists are joined with commas, as in -flang Go,Python.
+func configValue(value any) string {
+	list, ok := value.([]any)
+	if !ok {
+		return fmt.Sprint(value)
+	}
+	parts := make([]string, len(list))
+	for i, item := range list {
+		parts[i] = fmt.Sprint(item)
+	}
+	return strings.Join(parts, ",")
+}
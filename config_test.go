@@ -0,0 +1,115 @@
+package main_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	grepgithub "github.com/aviadhahami/grepgithub-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestConfigFileProvidesDefaults(t *testing.T) {
+	path := writeConfig(t, "flang: [Go, Python]\nfrepo: myorg\ndelay: 2s\njson: true\n")
+
+	args, err := grepgithub.ParseArguments([]string{"-config", path, "-q", "foo"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Go,Python", args.LangFilter)
+	assert.Equal(t, "myorg", args.RepoFilter)
+	assert.Equal(t, 2*time.Second, args.PageDelay)
+	assert.Equal(t, "json", args.Format)
+}
+
+func TestFlagsOverrideConfigFile(t *testing.T) {
+	path := writeConfig(t, "flang: Go\ndelay: 2s\n")
+
+	args, err := grepgithub.ParseArguments([]string{"-q", "foo", "-flang", "Rust", "-config", path})
+	assert.NoError(t, err)
+	assert.Equal(t, "Rust", args.LangFilter)
+	assert.Equal(t, 2*time.Second, args.PageDelay)
+}
+
+func TestDefaultConfigFile(t *testing.T) {
+	configDir, err := os.UserConfigDir()
+	assert.NoError(t, err)
+
+	// A missing default config is fine
+	_, err = grepgithub.ParseArguments([]string{"-q", "foo"})
+	assert.NoError(t, err)
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(configDir, "grepgithub"), 0o755))
+	path := filepath.Join(configDir, "grepgithub", "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("max-pages: 5\n"), 0o644))
+	defer os.Remove(path)
+
+	args, err := grepgithub.ParseArguments([]string{"-q", "foo"})
+	assert.NoError(t, err)
+	assert.Equal(t, 5, args.MaxPages)
+}
+
+func TestConfigFileErrors(t *testing.T) {
+	_, err := grepgithub.ParseArguments([]string{"-q", "foo", "-config", filepath.Join(t.TempDir(), "missing.yaml")})
+	assert.ErrorContains(t, err, "Cannot read config file")
+
+	path := writeConfig(t, "colour: always\n")
+	_, err = grepgithub.ParseArguments([]string{"-q", "foo", "-config", path})
+	assert.EqualError(t, err, `Unknown option "colour" in config file `+path)
+
+	path = writeConfig(t, "max-pages: lots\n")
+	_, err = grepgithub.ParseArguments([]string{"-q", "foo", "-config", path})
+	assert.ErrorContains(t, err, `Invalid value for "max-pages"`)
+}
+
+func TestEnvironmentProvidesDefaults(t *testing.T) {
+	t.Setenv("GREPGITHUB_LANG", "Go")
+	t.Setenv("GREPGITHUB_DELAY", "250ms")
+	t.Setenv("GREPGITHUB_BASE_URL", "http://mirror.example.com")
+
+	args, err := grepgithub.ParseArguments([]string{"-q", "foo"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Go", args.LangFilter)
+	assert.Equal(t, 250*time.Millisecond, args.PageDelay)
+	assert.Equal(t, "http://mirror.example.com", args.BaseURL)
+}
+
+func TestEnvironmentPrecedence(t *testing.T) {
+	path := writeConfig(t, "flang: Python\nfrepo: myorg\n")
+	t.Setenv("GREPGITHUB_LANG", "Go")
+	t.Setenv("GREPGITHUB_DELAY", "250ms")
+
+	args, err := grepgithub.ParseArguments([]string{"-config", path, "-q", "foo", "-delay", "3s"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Go", args.LangFilter)
+	assert.Equal(t, "myorg", args.RepoFilter)
+	assert.Equal(t, 3*time.Second, args.PageDelay)
+}
+
+func TestNoSleepOverridesDefaultDelay(t *testing.T) {
+	t.Setenv("GREPGITHUB_DELAY", "250ms")
+
+	args, err := grepgithub.ParseArguments([]string{"-q", "foo", "-no-sleep", "-quiet"})
+	assert.NoError(t, err)
+	assert.Zero(t, args.PageDelay)
+}
+
+func TestEnvironmentInvalidValue(t *testing.T) {
+	t.Setenv("GREPGITHUB_DELAY", "soon")
+
+	_, err := grepgithub.ParseArguments([]string{"-q", "foo"})
+	assert.ErrorContains(t, err, "Invalid value for GREPGITHUB_DELAY")
+}
+
+func TestConfigFormatDoesNotConflictWithCount(t *testing.T) {
+	path := writeConfig(t, "format: markdown\n")
+
+	args, err := grepgithub.ParseArguments([]string{"count", "-config", path, "-q", "foo"})
+	assert.NoError(t, err)
+	assert.True(t, args.Count)
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+)
+
+// loadSnapshot reads the hits of a previous -json output for -diff. Outputs
+// written before -json had a schema_version were a bare list of hits.
+func loadSnapshot(path string) (*grepapp.Results, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if data = bytes.TrimSpace(data); len(data) > 0 && data[0] == '[' {
+		var hits []grepapp.Result
+		if err := json.Unmarshal(data, &hits); err != nil {
+			return nil, err
+		}
+		return &grepapp.Results{Hits: hits}, nil
+	}
+	var doc jsonDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.SchemaVersion > schemaVersion {
+		return nil, fmt.Errorf("schema version %d is newer than this version of grepgithub", doc.SchemaVersion)
+	}
+	return &grepapp.Results{Hits: doc.Hits}, nil
+}
+
+// locations returns the repo, path and line number of every matched line
+// of hits, along with every file as line 0.
+func locations(hits *grepapp.Results) map[string]bool {
+	seen := make(map[string]bool)
+	for _, hit := range hits.Hits {
+		file := hit.Repo + "\x00" + hit.Path + "\x00"
+		seen[file+"0"] = true
+		for _, line := range hit.Lines {
+			if !line.Context {
+				seen[file+strconv.Itoa(line.LineNumber)] = true
+			}
+		}
+	}
+	return seen
+}
+
+func hasMatchedLines(hit *grepapp.Result) bool {
+	for _, line := range hit.Lines {
+		if !line.Context {
+			return true
+		}
+	}
+	return false
+}
+
+// subtract returns the files and matched lines of hits missing from seen,
+// as returned by locations. Context lines are left out.
+func subtract(hits *grepapp.Results, seen map[string]bool) *grepapp.Results {
+	missing := &grepapp.Results{Hits: []grepapp.Result{}}
+	for _, hit := range hits.Hits {
+		file := hit.Repo + "\x00" + hit.Path + "\x00"
+		if !hasMatchedLines(&hit) {
+			if !seen[file+"0"] {
+				missing.Merge(&grepapp.Results{Hits: []grepapp.Result{hit}})
+			}
+			continue
+		}
+		kept := hit
+		kept.Lines = nil
+		for _, line := range hit.Lines {
+			if !line.Context && !seen[file+strconv.Itoa(line.LineNumber)] {
+				kept.Lines = append(kept.Lines, line)
+			}
+		}
+		if len(kept.Lines) > 0 {
+			missing.Merge(&grepapp.Results{Hits: []grepapp.Result{kept}})
+		}
+	}
+	return missing
+}
+
+// diffHits compares current with previous by repo, path and line number. It
+// returns what current found that previous did not, and what disappeared.
+func diffHits(previous, current *grepapp.Results) (added, removed *grepapp.Results) {
+	return subtract(current, locations(previous)), subtract(previous, locations(current))
+}
+
+// prefixWriter writes a prefix at the start of every line that is not empty.
+type prefixWriter struct {
+	w      io.Writer
+	prefix string
+	midway bool
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	var out []byte
+	for _, c := range b {
+		if !p.midway && c != '\n' {
+			out = append(out, p.prefix...)
+		}
+		out = append(out, c)
+		p.midway = c != '\n'
+	}
+	if _, err := p.w.Write(out); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// writeDiff writes what -diff found: for text, the usual output of added
+// and removed hits with lines prefixed by + and -; for JSON, a document
+// with both lists; for JSON Lines, each file tagged with its change.
+func writeDiff(w io.Writer, added, removed *grepapp.Results, args *Arguments) error {
+	decorate(added, args)
+	decorate(removed, args)
+	switch args.Format {
+	case "json":
+		enc := json.NewEncoder(w)
+		if args.Pretty {
+			enc.SetIndent("", "  ")
+		}
+		return enc.Encode(struct {
+			Added   []grepapp.Result `json:"added"`
+			Removed []grepapp.Result `json:"removed"`
+		}{added.Hits, removed.Hits})
+	case "jsonl":
+		enc := json.NewEncoder(w)
+		for _, group := range []struct {
+			change string
+			hits   *grepapp.Results
+		}{{"added", added}, {"removed", removed}} {
+			for i := range group.hits.Hits {
+				if err := enc.Encode(struct {
+					Change string          `json:"change"`
+					Hit    *grepapp.Result `json:"hit"`
+				}{group.change, &group.hits.Hits[i]}); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := writeText(&prefixWriter{w: w, prefix: "+"}, added, args); err != nil {
+		return err
+	}
+	return writeText(&prefixWriter{w: w, prefix: "-"}, removed, args)
+}
@@ -0,0 +1,68 @@
+package main_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	grepgithub "github.com/aviadhahami/grepgithub-go"
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffHits(t *testing.T) {
+	previous := &grepapp.Results{}
+	previous.AddHit("example/repo", "main.go", 3, "foo")
+	previous.AddHit("example/repo", "main.go", 9, "foo")
+	previous.Merge(&grepapp.Results{Hits: []grepapp.Result{{Repo: "example/repo", Path: "README.md"}}})
+	current := &grepapp.Results{}
+	current.AddHit("example/repo", "main.go", 3, "foo")
+	current.AddHit("example/repo", "main.go", 12, "foo")
+	current.Merge(&grepapp.Results{Hits: []grepapp.Result{
+		{Repo: "example/repo", Path: "README.md"},
+		{Repo: "example/repo", Path: "util.go", Lines: []grepapp.Line{{LineNumber: 1, Text: "bar", Context: true}, {LineNumber: 2, Text: "foo"}}},
+	}})
+
+	added, removed := grepgithub.DiffHits(previous, current)
+	assert.Equal(t, []grepapp.Result{
+		{Repo: "example/repo", Path: "main.go", Lines: []grepapp.Line{{LineNumber: 12, Text: "foo"}}},
+		{Repo: "example/repo", Path: "util.go", Lines: []grepapp.Line{{LineNumber: 2, Text: "foo"}}},
+	}, added.Hits)
+	assert.Equal(t, []grepapp.Result{
+		{Repo: "example/repo", Path: "main.go", Lines: []grepapp.Line{{LineNumber: 9, Text: "foo"}}},
+	}, removed.Hits)
+
+	var buf bytes.Buffer
+	assert.NoError(t, grepgithub.WriteDiff(&buf, added, removed, &grepgithub.Arguments{Format: "text", Monochrome: true}))
+	assert.Equal(t, "+example/repo:main.go:12: foo\n+example/repo:util.go:2: foo\n-example/repo:main.go:9: foo\n", buf.String())
+}
+
+func TestRunDiff(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"facets":{"count":2},"hits":{"hits":[` +
+			`{"repo":{"raw":"example/repo"},"path":{"raw":"main.go"}},` +
+			`{"repo":{"raw":"example/repo"},"path":{"raw":"new.go"}}]}}`))
+	}))
+	defer server.Close()
+	dir := t.TempDir()
+	snapshot := filepath.Join(dir, "previous.json")
+	legacy := filepath.Join(dir, "legacy.json")
+	assert.NoError(t, os.WriteFile(snapshot, []byte(`{"schema_version":1,"query":{"query":"foo"},"hits":[{"repo":"example/repo","path":"main.go","lines":[]},{"repo":"example/repo","path":"old.go","lines":[]}]}`), 0o644))
+	assert.NoError(t, os.WriteFile(legacy, []byte(`[{"repo":"example/repo","path":"main.go","lines":[]}]`), 0o644))
+
+	for path, want := range map[string]string{
+		snapshot: `{"change":"added","hit":{"repo":"example/repo","path":"new.go","lang":"Go","lines":[]}}` + "\n" +
+			`{"change":"removed","hit":{"repo":"example/repo","path":"old.go","lines":[]}}` + "\n",
+		legacy: `{"change":"added","hit":{"repo":"example/repo","path":"new.go","lang":"Go","lines":[]}}` + "\n",
+	} {
+		args, err := grepgithub.ParseArguments([]string{"-q", "foo", "-delay", "0", "-jsonl", "-base-url", server.URL, "-diff", path})
+		assert.NoError(t, err)
+		var buf bytes.Buffer
+		assert.NoError(t, grepgithub.Run(context.Background(), args, &buf))
+		assert.Equal(t, want, buf.String())
+	}
+}
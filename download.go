@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+)
+
+// downloadFiles saves the raw content of every matched file under
+// dir/<repo>/<path>, waiting the client's page delay between downloads.
+// Files already present are kept, and files that are not in a GitHub
+// repository or cannot be downloaded are skipped with a warning on warn.
+func downloadFiles(ctx context.Context, client *grepapp.Client, hits *grepapp.Results, dir string, warn io.Writer) error {
+	failed := 0
+	first := true
+	for _, hit := range hits.Hits {
+		link := grepapp.RawURL(hit.Repo, hit.Path)
+		local := filepath.Join(hit.Repo, filepath.FromSlash(hit.Path))
+		switch {
+		case link == "":
+			fmt.Fprintf(warn, "Warning: skipping %s/%s, %s is not a GitHub repository\n", hit.Repo, hit.Path, hit.Repo)
+			continue
+		case !filepath.IsLocal(local):
+			fmt.Fprintf(warn, "Warning: skipping %s/%s, it would be saved outside %s\n", hit.Repo, hit.Path, dir)
+			continue
+		}
+		dest := filepath.Join(dir, local)
+		if _, err := os.Stat(dest); err == nil {
+			continue
+		}
+
+		if !first {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(client.PageDelay):
+			}
+		}
+		first = false
+		if err := download(ctx, client, link, dest); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			fmt.Fprintf(warn, "Warning: cannot download %s/%s: %s\n", hit.Repo, hit.Path, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files could not be downloaded", failed, len(hits.Hits))
+	}
+	return nil
+}
+
+// download saves the body of link to dest, creating its directory. dest is
+// only created once the whole body was received.
+func download(ctx context.Context, client *grepapp.Client, link, dest string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return err
+	}
+	if client.UserAgent != "" {
+		req.Header.Set("User-Agent", client.UserAgent)
+	}
+	resp, err := client.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return writeFile(dest, resp.Body)
+}
+
+// writeFile writes the content of r to path through a temporary file, so
+// that path never holds partial content, creating its directory if needed.
+func writeFile(path string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	// CreateTemp makes files only readable by their owner
+	if err == nil {
+		err = os.Chmod(f.Name(), 0o644)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
@@ -0,0 +1,69 @@
+package main_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	grepgithub "github.com/aviadhahami/grepgithub-go"
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+	"github.com/stretchr/testify/assert"
+)
+
+// redirect sends every request to server, whatever its host.
+type redirect struct {
+	server *httptest.Server
+}
+
+func (r redirect) RoundTrip(req *http.Request) (*http.Response, error) {
+	target, _ := url.Parse(r.server.URL)
+	req = req.Clone(req.Context())
+	req.URL.Scheme, req.URL.Host = target.Scheme, target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestDownloadFiles(t *testing.T) {
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		if r.URL.Path == "/example/repo/HEAD/missing.go" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("content of " + r.URL.Path))
+	}))
+	defer server.Close()
+	client := grepapp.NewClient()
+	client.HTTPClient = &http.Client{Transport: redirect{server}}
+	client.PageDelay = 0
+
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "example/repo"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "example/repo/README.md"), []byte("kept"), 0o644))
+
+	hits := &grepapp.Results{}
+	hits.AddHit("example/repo", "cmd/main.go", 3, "foo")
+	hits.AddHit("example/repo", "README.md", 1, "foo")
+	hits.AddHit("example/repo", "missing.go", 1, "foo")
+	hits.AddHit("git.example.com/repo", "main.go", 1, "foo")
+
+	var warn bytes.Buffer
+	err := grepgithub.DownloadFiles(context.Background(), client, hits, dir, &warn)
+	assert.EqualError(t, err, "1 of 4 files could not be downloaded")
+	assert.Equal(t, []string{"/example/repo/HEAD/cmd/main.go", "/example/repo/HEAD/missing.go"}, requested)
+	assert.Equal(t, "Warning: cannot download example/repo/missing.go: unexpected status 404 Not Found\n"+
+		"Warning: skipping git.example.com/repo/main.go, git.example.com/repo is not a GitHub repository\n", warn.String())
+
+	data, err := os.ReadFile(filepath.Join(dir, "example/repo/cmd/main.go"))
+	assert.NoError(t, err)
+	assert.Equal(t, "content of /example/repo/HEAD/cmd/main.go", string(data))
+	data, err = os.ReadFile(filepath.Join(dir, "example/repo/README.md"))
+	assert.NoError(t, err)
+	assert.Equal(t, "kept", string(data))
+	assert.NoFileExists(t, filepath.Join(dir, "example/repo/missing.go"))
+}
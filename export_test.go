@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+)
+
+var (
+	ParseArguments    = parseArguments
+	UseColor          = useColor
+	NewClient         = newClient
+	NewStars          = newStars
+	NewGithubLimiter  = newGithubLimiter
+	ErrGithubLimited  = errGithubRateLimited
+	SkipGithubWait    = skipGithubWait
+	Run               = run
+	ErrNoMatches      = errNoMatches
+	ErrorMessage      = errorMessage
+	ErrTimeout        = errTimeout
+	PrintURLs         = printURLs
+	Render            = render
+	Exclude           = exclude
+	MaxFilesPerRepo   = maxFilesPerRepo
+	FilterLines       = filterLines
+	ExcludingText     = excludingText
+	ContainsText      = containsText
+	UnknownLanguages  = unknownLanguages
+	Levenshtein       = levenshtein
+	Limit             = limit
+	MinMatches        = minMatches
+	FirstLineOnly     = firstLineOnly
+	NewSeenSet        = newSeenSet
+	ParseExtensions   = parseExtensions
+	KeepExtensions    = keepExtensions
+	SortHits          = sortHits
+	WriteText         = writeText
+	WriteJSON         = writeJSON
+	WriteJSONL        = writeJSONL
+	WriteEvents       = writeEvents
+	WriteLineRecords  = writeLineRecords
+	ColorJSON         = colorJSON
+	WriteCSV          = writeCSV
+	WriteMarkdown     = writeMarkdown
+	WriteSARIF        = writeSARIF
+	WriteJUnit        = writeJUnit
+	WriteSQL          = writeSQL
+	DiffHits          = diffHits
+	WriteDiff         = writeDiff
+	NewWebhookPayload = newWebhookPayload
+	PostWebhook       = postWebhook
+	WriteSQLite       = writeSQLite
+	WriteStats        = writeStats
+	WriteFields       = writeFields
+	GroupBy           = groupBy
+	ByRepo            = byRepo
+	ByLang            = byLang
+	WriteGroups       = writeGroups
+	Distinct          = distinct
+	WriteList         = writeList
+	NewBrowser        = newBrowser
+	ReadKey           = readKey
+	Truncate          = truncate
+	CopyToClipboard   = copyToClipboard
+	ErrNoClipboard    = errNoClipboard
+	ClipText          = clipText
+	DownloadFiles     = downloadFiles
+	OpenCommand       = openCommand
+	OpenFiles         = openFiles
+)
+
+func (p *progress) Estimate(page *grepapp.Page) { p.estimate(page) }
+func (p *progress) Update(page, hits int)       { p.update(page, hits) }
+func (p *progress) Clear()                      { p.clear() }
+
+func NewProgress(w io.Writer, maxPages int) *progress {
+	return &progress{w: w, maxPages: maxPages}
+}
+
+func (args *Arguments) ListKey() func(*grepapp.Result) string {
+	return args.listKey()
+}
+
+func (b *browser) Handle(key string) bool   { return b.handle(key) }
+func (b *browser) Current() *grepapp.Result { return b.current() }
+func (b *browser) Draw(w io.Writer, width, height int, color string) {
+	b.draw(w, width, height, color)
+}
+
+func (s *seenSet) Add(key string) bool { return s.add(key) }
+func (s *seenSet) Dedup(page *grepapp.Results, files *int, max int) bool {
+	return s.dedup(page, files, max)
+}
+
+func (s *stars) Annotate(ctx context.Context, hits *grepapp.Results) error {
+	return s.annotate(ctx, hits)
+}
+
+func (l *githubLimiter) SetClock(now func() time.Time, after func(time.Duration) <-chan time.Time) {
+	l.now, l.after = now, after
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+)
+
+// fileFields and lineFields are the names accepted by -fields. Selecting any
+// line field writes one record per matched line instead of one per file.
+var (
+	fileFields = []string{"repo", "path", "lang", "url", "stars"}
+	lineFields = []string{"line_number", "text"}
+)
+
+// parseFields splits a comma separated list of field names, rejecting
+// unknown and repeated names.
+func parseFields(value string) ([]string, error) {
+	var fields []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if !slices.Contains(fileFields, name) && !slices.Contains(lineFields, name) {
+			return nil, fmt.Errorf("Unknown field %q, expected some of %s", name, strings.Join(append(slices.Clone(fileFields), lineFields...), ","))
+		}
+		if slices.Contains(fields, name) {
+			return nil, fmt.Errorf("Field %q is repeated", name)
+		}
+		fields = append(fields, name)
+	}
+	return fields, nil
+}
+
+// record holds the selected fields of a file or line in the order they were
+// given.
+type record struct {
+	names  []string
+	values []any
+}
+
+func (r record) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, name := range r.names {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(name)
+		value, err := json.Marshal(r.values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (r record) strings() []string {
+	s := make([]string, len(r.values))
+	for i, value := range r.values {
+		if value != nil {
+			s[i] = fmt.Sprint(value)
+		}
+	}
+	return s
+}
+
+func newRecord(fields []string, hit *grepapp.Result, line *grepapp.Line) record {
+	r := record{names: fields}
+	for _, name := range fields {
+		var value any
+		switch name {
+		case "repo":
+			value = hit.Repo
+		case "path":
+			value = hit.Path
+		case "lang":
+			value = hit.Lang
+		case "url":
+			value = hit.URL
+			if line != nil {
+				value = line.URL
+			}
+		case "stars":
+			value = ""
+			if hit.Stars != nil {
+				value = *hit.Stars
+			}
+		case "line_number":
+			// Files without lines have no line number
+			value = nil
+			if line != nil {
+				value = line.LineNumber
+			}
+		case "text":
+			value = ""
+			if line != nil {
+				value = line.Text
+			}
+		}
+		r.values = append(r.values, value)
+	}
+	return r
+}
+
+// records selects fields from hits, one record per file or, when a line field
+// is selected, per matched line. Context lines are left out, and files
+// without matched lines still get a record.
+func records(hits *grepapp.Results, fields []string) []record {
+	perLine := slices.ContainsFunc(fields, func(name string) bool {
+		return slices.Contains(lineFields, name)
+	})
+	records := []record{}
+	for i := range hits.Hits {
+		hit := &hits.Hits[i]
+		if !perLine || hit.MatchedLines() == 0 {
+			records = append(records, newRecord(fields, hit, nil))
+			continue
+		}
+		for j := range hit.Lines {
+			if hit.Lines[j].Context {
+				continue
+			}
+			records = append(records, newRecord(fields, hit, &hit.Lines[j]))
+		}
+	}
+	return records
+}
+
+// writeFields writes only the fields selected with -fields in the selected
+// output format.
+func writeFields(w io.Writer, hits *grepapp.Results, args *Arguments) error {
+	records := records(hits, args.Fields)
+	switch args.Format {
+	case "json":
+		enc := json.NewEncoder(w)
+		if args.Pretty {
+			enc.SetIndent("", "  ")
+		}
+		return enc.Encode(records)
+	case "jsonl":
+		enc := json.NewEncoder(w)
+		for _, r := range records {
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write(args.Fields); err != nil {
+			return err
+		}
+		for _, r := range records {
+			row := r.strings()
+			for i := range row {
+				row[i] = stripANSI(row[i])
+			}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	for _, r := range records {
+		if _, err := fmt.Fprintln(w, strings.Join(r.strings(), ":")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package main_test
+
+import (
+	"bytes"
+	"testing"
+
+	grepgithub "github.com/aviadhahami/grepgithub-go"
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+	"github.com/stretchr/testify/assert"
+)
+
+func fieldHits() *grepapp.Results {
+	hits := &grepapp.Results{}
+	hits.AddHit("example/repo", "main.go", 3, "foo")
+	hits.AddHit("example/repo", "main.go", 8, "bar")
+	hits.Merge(&grepapp.Results{Hits: []grepapp.Result{{Repo: "example/repo", Path: "main.go", Lines: []grepapp.Line{{LineNumber: 4, Text: "context", Context: true}}}}})
+	hits.Merge(&grepapp.Results{Hits: []grepapp.Result{{Repo: "example/repo", Path: "README.md"}}})
+	return hits
+}
+
+func TestWriteFieldsPerFile(t *testing.T) {
+	args, err := grepgithub.ParseArguments([]string{"-q", "foo", "-fields", "path,repo", "-csv"})
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, grepgithub.WriteFields(&buf, fieldHits(), args))
+	assert.Equal(t, "path,repo\n"+
+		"main.go,example/repo\n"+
+		"README.md,example/repo\n", buf.String())
+}
+
+func TestWriteFieldsPerLine(t *testing.T) {
+	args, err := grepgithub.ParseArguments([]string{"-q", "foo", "-fields", "path,line_number", "-jsonl"})
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, grepgithub.WriteFields(&buf, fieldHits(), args))
+	assert.Equal(t, `{"path":"main.go","line_number":3}`+"\n"+
+		`{"path":"main.go","line_number":8}`+"\n"+
+		`{"path":"README.md","line_number":null}`+"\n", buf.String())
+
+	args.Format = "text"
+	buf.Reset()
+	assert.NoError(t, grepgithub.WriteFields(&buf, fieldHits(), args))
+	assert.Equal(t, "main.go:3\nmain.go:8\nREADME.md:\n", buf.String())
+}
+
+func TestFieldsURLAddsLinks(t *testing.T) {
+	args, err := grepgithub.ParseArguments([]string{"-q", "foo", "-fields", "url", "-json"})
+	assert.NoError(t, err)
+	assert.True(t, args.Links)
+
+	var buf bytes.Buffer
+	assert.NoError(t, grepgithub.Render(&buf, fieldHits(), args))
+	assert.Equal(t, `[{"url":"https://github.com/example/repo/blob/HEAD/main.go"},{"url":"https://github.com/example/repo/blob/HEAD/README.md"}]`+"\n", buf.String())
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+)
+
+// exclude drops the hits whose repository or path matches the given
+// patterns. Nil patterns exclude nothing.
+func exclude(hits *grepapp.Results, repo, path *regexp.Regexp) {
+	kept := hits.Hits[:0]
+	for _, hit := range hits.Hits {
+		if repo != nil && repo.MatchString(hit.Repo) || path != nil && path.MatchString(hit.Path) {
+			continue
+		}
+		kept = append(kept, hit)
+	}
+	hits.Hits = kept
+}
+
+// filterLines keeps the matched lines whose text, without highlighting,
+// satisfies keep, along with the context lines up to before and after them.
+// Hits left without matched lines are dropped.
+func filterLines(hits *grepapp.Results, keep func(string) bool, before, after int) {
+	kept := hits.Hits[:0]
+	for _, hit := range hits.Hits {
+		var matched []int
+		for _, line := range hit.Lines {
+			if !line.Context && keep(stripANSI(line.Text)) {
+				matched = append(matched, line.LineNumber)
+			}
+		}
+		if len(matched) == 0 {
+			continue
+		}
+		lines := []grepapp.Line{}
+		for _, line := range hit.Lines {
+			if !line.Context {
+				if slices.Contains(matched, line.LineNumber) {
+					lines = append(lines, line)
+				}
+				continue
+			}
+			for _, number := range matched {
+				if line.LineNumber >= number-before && line.LineNumber <= number+after {
+					lines = append(lines, line)
+					break
+				}
+			}
+		}
+		hit.Lines = lines
+		kept = append(kept, hit)
+	}
+	hits.Hits = kept
+}
+
+// containsText returns a filterLines test for lines containing text,
+// ignoring case unless caseSensitive.
+func containsText(text string, caseSensitive bool) func(string) bool {
+	if caseSensitive {
+		return func(line string) bool { return strings.Contains(line, text) }
+	}
+	text = strings.ToLower(text)
+	return func(line string) bool { return strings.Contains(strings.ToLower(line), text) }
+}
+
+// excludingText returns a filterLines test for lines not matching re.
+func excludingText(re *regexp.Regexp) func(string) bool {
+	return func(line string) bool { return !re.MatchString(line) }
+}
+
+// parseExtensions splits a comma separated list of file extensions, adding
+// the leading dot where it is missing.
+func parseExtensions(value string) ([]string, error) {
+	var exts []string
+	for _, ext := range strings.Split(value, ",") {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" || ext == "." {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		exts = append(exts, ext)
+	}
+	if len(exts) == 0 {
+		return nil, errors.New("expected comma separated extensions such as .proto,.tmpl")
+	}
+	return exts, nil
+}
+
+// keepExtensions drops the hits whose path does not end with one of exts,
+// ignoring case. No extensions keep every hit.
+func keepExtensions(hits *grepapp.Results, exts []string) {
+	if len(exts) == 0 {
+		return
+	}
+	kept := hits.Hits[:0]
+	for _, hit := range hits.Hits {
+		path := strings.ToLower(hit.Path)
+		for _, ext := range exts {
+			if strings.HasSuffix(path, ext) {
+				kept = append(kept, hit)
+				break
+			}
+		}
+	}
+	hits.Hits = kept
+}
+
+// firstLineOnly keeps only the lowest numbered matched line of each hit,
+// dropping context lines too. Lines are kept sorted by number, so the first
+// match is the lowest.
+func firstLineOnly(hits *grepapp.Results) {
+	for i := range hits.Hits {
+		hit := &hits.Hits[i]
+		for _, line := range hit.Lines {
+			if !line.Context {
+				hit.Lines = []grepapp.Line{line}
+				break
+			}
+		}
+	}
+}
+
+// limit trims page so that merging it into hits only adds files while hits
+// holds fewer than max of them, and reports whether max has been reached.
+// Lines for files that were already collected are always kept. A max of 0
+// means no limit.
+func limit(page, hits *grepapp.Results, max int) bool {
+	if max <= 0 {
+		return false
+	}
+
+	type file struct{ repo, path string }
+	seen := make(map[file]bool, len(hits.Hits))
+	for _, hit := range hits.Hits {
+		seen[file{hit.Repo, hit.Path}] = true
+	}
+
+	total := len(hits.Hits)
+	kept := page.Hits[:0]
+	for _, hit := range page.Hits {
+		key := file{hit.Repo, hit.Path}
+		if !seen[key] {
+			if total >= max {
+				continue
+			}
+			seen[key] = true
+			total++
+		}
+		kept = append(kept, hit)
+	}
+	page.Hits = kept
+	return total >= max
+}
+
+// maxFilesPerRepo keeps the first max hits of each repository, in the
+// order grep.app returned them, so that a monorepo does not crowd out other
+// projects. A max of 0 keeps every hit.
+func maxFilesPerRepo(hits *grepapp.Results, max int) {
+	if max <= 0 {
+		return
+	}
+	files := map[string]int{}
+	kept := hits.Hits[:0]
+	for _, hit := range hits.Hits {
+		if files[hit.Repo] < max {
+			files[hit.Repo]++
+			kept = append(kept, hit)
+		}
+	}
+	hits.Hits = kept
+}
+
+// minMatches drops the hits of repositories with fewer than min matched
+// files. A min of 0 or 1 keeps every hit.
+func minMatches(hits *grepapp.Results, min int) {
+	if min <= 1 {
+		return
+	}
+	files := map[string]int{}
+	for _, hit := range hits.Hits {
+		files[hit.Repo]++
+	}
+	kept := hits.Hits[:0]
+	for _, hit := range hits.Hits {
+		if files[hit.Repo] >= min {
+			kept = append(kept, hit)
+		}
+	}
+	hits.Hits = kept
+}
@@ -0,0 +1,186 @@
+package main_test
+
+import (
+	"regexp"
+	"testing"
+
+	grepgithub "github.com/aviadhahami/grepgithub-go"
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+	"github.com/stretchr/testify/assert"
+)
+
+func paths(hits *grepapp.Results) []string {
+	var paths []string
+	for _, hit := range hits.Hits {
+		paths = append(paths, hit.Repo+"/"+hit.Path)
+	}
+	return paths
+}
+
+func TestExclude(t *testing.T) {
+	newHits := func() *grepapp.Results {
+		hits := &grepapp.Results{}
+		hits.AddHit("me/fork", "main.go", 1, "a")
+		hits.AddHit("me/forked-tool", "cmd/main.go", 1, "b")
+		hits.AddHit("upstream/tool", "main.go", 1, "c")
+		hits.AddHit("upstream/tool", "vendor/lib/lib.go", 1, "d")
+		return hits
+	}
+
+	hits := newHits()
+	grepgithub.Exclude(hits, regexp.MustCompile("me/fork"), nil)
+	assert.Equal(t, []string{"upstream/tool/main.go", "upstream/tool/vendor/lib/lib.go"}, paths(hits))
+
+	hits = newHits()
+	grepgithub.Exclude(hits, regexp.MustCompile("^me/fork$"), regexp.MustCompile(`^vendor/`))
+	assert.Equal(t, []string{"me/forked-tool/cmd/main.go", "upstream/tool/main.go"}, paths(hits))
+
+	hits = newHits()
+	grepgithub.Exclude(hits, nil, nil)
+	assert.Len(t, hits.Hits, 4)
+}
+
+func TestLimit(t *testing.T) {
+	hits := &grepapp.Results{}
+	page1 := &grepapp.Results{}
+	page1.AddHit("example/repo", "a.go", 1, "a")
+	page1.AddHit("example/repo", "b.go", 1, "b")
+
+	assert.False(t, grepgithub.Limit(page1, hits, 3))
+	hits.Merge(page1)
+
+	page2 := &grepapp.Results{}
+	page2.AddHit("example/repo", "a.go", 7, "a again")
+	page2.AddHit("example/repo", "c.go", 1, "c")
+	page2.AddHit("example/repo", "d.go", 1, "d")
+
+	assert.True(t, grepgithub.Limit(page2, hits, 3))
+	assert.Equal(t, []string{"example/repo/a.go", "example/repo/c.go"}, paths(page2))
+	hits.Merge(page2)
+	assert.Len(t, hits.Hits, 3)
+	assert.Len(t, hits.Hits[0].Lines, 2)
+
+	unlimited := &grepapp.Results{}
+	unlimited.AddHit("example/repo", "e.go", 1, "e")
+	assert.False(t, grepgithub.Limit(unlimited, hits, 0))
+	assert.Len(t, unlimited.Hits, 1)
+}
+
+func TestMinMatches(t *testing.T) {
+	hits := &grepapp.Results{}
+	hits.AddHit("example/busy", "a.go", 1, "a")
+	hits.AddHit("example/once", "a.go", 1, "a")
+	hits.AddHit("example/busy", "b.go", 1, "b")
+	hits.AddHit("example/busy", "b.go", 2, "b")
+
+	grepgithub.MinMatches(hits, 1)
+	assert.Len(t, hits.Hits, 3)
+	grepgithub.MinMatches(hits, 2)
+	assert.Equal(t, []string{"example/busy/a.go", "example/busy/b.go"}, paths(hits))
+	grepgithub.MinMatches(hits, 3)
+	assert.Empty(t, hits.Hits)
+}
+
+func TestMaxFilesPerRepo(t *testing.T) {
+	hits := &grepapp.Results{}
+	hits.AddHit("example/mono", "z.go", 1, "a")
+	hits.AddHit("example/small", "a.go", 1, "a")
+	hits.AddHit("example/mono", "b.go", 1, "b")
+	hits.AddHit("example/mono", "a.go", 1, "c")
+
+	grepgithub.MaxFilesPerRepo(hits, 0)
+	assert.Len(t, hits.Hits, 4)
+	grepgithub.MaxFilesPerRepo(hits, 2)
+	assert.Equal(t, []string{"example/mono/z.go", "example/small/a.go", "example/mono/b.go"}, paths(hits), "the first files returned are kept")
+	grepgithub.MaxFilesPerRepo(hits, 1)
+	assert.Equal(t, []string{"example/mono/z.go", "example/small/a.go"}, paths(hits))
+}
+
+func TestKeepExtensions(t *testing.T) {
+	exts, err := grepgithub.ParseExtensions(" proto, .TMPL,,")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{".proto", ".tmpl"}, exts)
+
+	hits := &grepapp.Results{}
+	hits.AddHit("example/repo", "api/service.proto", 1, "a")
+	hits.AddHit("example/repo", "views/Index.TMPL", 1, "b")
+	hits.AddHit("example/repo", "proto", 1, "c")
+	hits.AddHit("example/repo", "main.go", 1, "d")
+
+	grepgithub.KeepExtensions(hits, nil)
+	assert.Len(t, hits.Hits, 4)
+	grepgithub.KeepExtensions(hits, exts)
+	assert.Equal(t, []string{"example/repo/api/service.proto", "example/repo/views/Index.TMPL"}, paths(hits))
+}
+
+func TestFirstLineOnly(t *testing.T) {
+	hits := &grepapp.Results{}
+	hits.AddHit("example/repo", "a.go", 9, "later")
+	hits.AddHit("example/repo", "a.go", 4, "first")
+	hits.Merge(&grepapp.Results{Hits: []grepapp.Result{{Repo: "example/repo", Path: "a.go", Lines: []grepapp.Line{{LineNumber: 3, Text: "context", Context: true}}}}})
+	hits.Merge(&grepapp.Results{Hits: []grepapp.Result{{Repo: "example/repo", Path: "b.go"}}})
+
+	grepgithub.FirstLineOnly(hits)
+	assert.Equal(t, []grepapp.Line{{LineNumber: 4, Text: "first"}}, hits.Hits[0].Lines)
+	assert.Empty(t, hits.Hits[1].Lines)
+}
+
+func TestFilterLinesContainingText(t *testing.T) {
+	newHits := func() *grepapp.Results {
+		hits := &grepapp.Results{}
+		hits.AddHit("example/repo", "a.go", 3, "client."+grepapp.C_MARK+"Do"+grepapp.C_RST+"(req, Timeout)")
+		hits.AddHit("example/repo", "a.go", 9, "client."+grepapp.C_MARK+"Do"+grepapp.C_RST+"(req)")
+		hits.AddHit("example/repo", "b.go", 5, "client."+grepapp.C_MARK+"Do"+grepapp.C_RST+"(other)")
+		hits.Merge(&grepapp.Results{Hits: []grepapp.Result{{Repo: "example/repo", Path: "a.go", Lines: []grepapp.Line{
+			{LineNumber: 2, Text: "// before the kept match", Context: true},
+			{LineNumber: 10, Text: "// after the dropped match", Context: true},
+		}}}})
+		hits.Merge(&grepapp.Results{Hits: []grepapp.Result{{Repo: "example/repo", Path: "c.go"}}})
+		return hits
+	}
+
+	hits := newHits()
+	grepgithub.FilterLines(hits, grepgithub.ContainsText("timeout", false), 1, 1)
+	assert.Equal(t, []string{"example/repo/a.go"}, paths(hits))
+	var numbers []int
+	for _, line := range hits.Hits[0].Lines {
+		numbers = append(numbers, line.LineNumber)
+	}
+	assert.Equal(t, []int{2, 3}, numbers)
+
+	hits = newHits()
+	grepgithub.FilterLines(hits, grepgithub.ContainsText("timeout", true), 1, 1)
+	assert.Empty(t, hits.Hits)
+
+	hits = newHits()
+	grepgithub.FilterLines(hits, grepgithub.ContainsText("client.Do(req", false), 0, 0)
+	assert.Equal(t, []string{"example/repo/a.go"}, paths(hits))
+	assert.Len(t, hits.Hits[0].Lines, 2, "highlighting does not get in the way")
+}
+
+func TestExcludeText(t *testing.T) {
+	newHits := func() *grepapp.Results {
+		hits := &grepapp.Results{}
+		hits.AddHit("example/repo", "a.go", 3, "// "+grepapp.C_MARK+"token"+grepapp.C_RST+" = example")
+		hits.AddHit("example/repo", "a.go", 7, "	  # "+grepapp.C_MARK+"token"+grepapp.C_RST+" in a comment")
+		hits.AddHit("example/repo", "b.go", 5, grepapp.C_MARK+"token"+grepapp.C_RST+" := load()")
+		return hits
+	}
+	run := func(pattern string) *grepapp.Results {
+		args, err := grepgithub.ParseArguments([]string{"-q", "token", "-exclude-text", pattern})
+		assert.NoError(t, err)
+		hits := newHits()
+		grepgithub.FilterLines(hits, grepgithub.ExcludingText(args.TextExclude), 0, 0)
+		return hits
+	}
+
+	hits := run("example")
+	assert.Equal(t, []string{"example/repo/a.go", "example/repo/b.go"}, paths(hits))
+	assert.Equal(t, 7, hits.Hits[0].Lines[0].LineNumber)
+
+	hits = run(`^\s*(//|#)`)
+	assert.Equal(t, []string{"example/repo/b.go"}, paths(hits))
+
+	_, err := grepgithub.ParseArguments([]string{"-q", "token", "-exclude-text", "("})
+	assert.ErrorContains(t, err, `invalid value "(" for flag -exclude-text`)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// GitHub's hourly request limits, without and with -github-token.
+const (
+	githubRate      = 60
+	githubTokenRate = 5000
+)
+
+// githubHosts are the GitHub hosts -with-stars and -download send requests
+// to. Each gets its own bucket, as GitHub limits them separately.
+var githubHosts = map[string]bool{
+	"api.github.com":            true,
+	"raw.githubusercontent.com": true,
+}
+
+// githubReserve is the number of requests left in GitHub's limit at which
+// a host is paused. GitHub's count includes requests in flight and those of
+// other tools sharing the token or address, so waiting for it to reach 0
+// risks being refused.
+const githubReserve = 3
+
+// errGithubRateLimited is returned instead of sending a request to a GitHub
+// host that is out of requests, when the request was made with
+// skipGithubWait.
+var errGithubRateLimited = errors.New("GitHub rate limit reached")
+
+type skipGithubWaitKey struct{}
+
+// skipGithubWait returns a context for requests that fail with
+// errGithubRateLimited rather than wait for the limit, so that -with-stars
+// leaves stars unknown instead of holding up the search.
+func skipGithubWait(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipGithubWaitKey{}, true)
+}
+
+// githubLimiter is a token bucket per GitHub host, allowing rate requests an
+// hour after a burst. Requests to other hosts, like grep.app's, go through
+// untouched. Once a response reports that few requests remain, the host is
+// paused until GitHub resets the limit. Requests wait for a token or for
+// the pause to end, unless made with skipGithubWait.
+type githubLimiter struct {
+	next     http.RoundTripper
+	interval time.Duration
+	burst    int
+	warn     io.Writer
+	now      func() time.Time
+	after    func(time.Duration) <-chan time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	// resume is when GitHub said it accepts requests again
+	resume time.Time
+}
+
+// newGithubLimiter limits GitHub requests sent through next to rate an
+// hour, allowing bursts of up to burst requests. Pauses are reported to
+// warn.
+func newGithubLimiter(next http.RoundTripper, rate, burst int, warn io.Writer) *githubLimiter {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &githubLimiter{
+		next:     next,
+		interval: time.Hour / time.Duration(rate),
+		burst:    burst,
+		warn:     warn,
+		now:      time.Now,
+		after:    time.After,
+		buckets:  make(map[string]*bucket),
+	}
+}
+
+func (l *githubLimiter) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	if !githubHosts[host] {
+		return l.next.RoundTrip(req)
+	}
+	if err := l.wait(req.Context(), host); err != nil {
+		return nil, err
+	}
+	resp, err := l.next.RoundTrip(req)
+	if err == nil {
+		l.observe(host, resp.Header)
+	}
+	return resp, err
+}
+
+// wait takes a token from the bucket of host, waiting for one to be added
+// or for a pause to end. Contexts from skipGithubWait get
+// errGithubRateLimited instead.
+func (l *githubLimiter) wait(ctx context.Context, host string) error {
+	skip, _ := ctx.Value(skipGithubWaitKey{}).(bool)
+	for {
+		delay, ok := l.take(host)
+		switch {
+		case ok:
+			return nil
+		case skip:
+			return errGithubRateLimited
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-l.after(delay):
+		}
+	}
+}
+
+// take takes a token from the bucket of host. When there is none left or the
+// host is paused, it reports false and how long until there may be one.
+func (l *githubLimiter) take(host string) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	b, ok := l.buckets[host]
+	if !ok {
+		b = &bucket{tokens: float64(l.burst), last: now}
+		l.buckets[host] = b
+	}
+	b.tokens = min(float64(l.burst), b.tokens+float64(now.Sub(b.last))/float64(l.interval))
+	b.last = now
+	switch {
+	case now.Before(b.resume):
+		return b.resume.Sub(now), false
+	case b.tokens < 1:
+		return time.Duration((1 - b.tokens) * float64(l.interval)), false
+	}
+	b.tokens--
+	return 0, true
+}
+
+// observe pauses host until the limit resets once X-RateLimit-Remaining
+// drops to githubReserve.
+func (l *githubLimiter) observe(host string, header http.Header) {
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err != nil || remaining > githubReserve {
+		return
+	}
+	reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+	resume := time.Unix(reset, 0)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b := l.buckets[host]
+	if !resume.After(b.resume) || !resume.After(l.now()) {
+		return
+	}
+	b.resume = resume
+	fmt.Fprintf(l.warn, "Warning: GitHub rate limit almost reached, pausing requests to %s until %s\n", host, resume.Format(time.TimeOnly))
+}
@@ -0,0 +1,131 @@
+package main_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	grepgithub "github.com/aviadhahami/grepgithub-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeTransport answers every request with header, recording its host.
+type fakeTransport struct {
+	hosts  []string
+	header http.Header
+}
+
+func (f *fakeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.hosts = append(f.hosts, req.URL.Host)
+	return &http.Response{StatusCode: http.StatusOK, Header: f.header.Clone(), Body: http.NoBody, Request: req}, nil
+}
+
+// fakeClock is a clock for githubLimiter that only moves when told to or
+// waited on, recording the waits.
+type fakeClock struct {
+	t     time.Time
+	waits []time.Duration
+}
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func (c *fakeClock) after(d time.Duration) <-chan time.Time {
+	c.waits = append(c.waits, d)
+	c.advance(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.t
+	return ch
+}
+
+// get sends a request for link through rt, returning the error of rt.
+func get(t *testing.T, rt http.RoundTripper, link string) error {
+	return getContext(t, context.Background(), rt, link)
+}
+
+// getContext is get with a context for the request.
+func getContext(t *testing.T, ctx context.Context, rt http.RoundTripper, link string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	assert.NoError(t, err)
+	resp, err := rt.RoundTrip(req)
+	if err == nil {
+		resp.Body.Close()
+	}
+	return err
+}
+
+func TestGithubLimiterSpacesRequests(t *testing.T) {
+	next := &fakeTransport{header: http.Header{}}
+	clock := &fakeClock{t: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	// 60 an hour is one request a minute once the burst of 2 is spent
+	limiter := grepgithub.NewGithubLimiter(next, 60, 2, &bytes.Buffer{})
+	limiter.SetClock(clock.now, clock.after)
+
+	assert.NoError(t, get(t, limiter, "https://api.github.com/repos/example/a"))
+	assert.NoError(t, get(t, limiter, "https://api.github.com/repos/example/b"))
+	assert.NoError(t, get(t, limiter, "https://raw.githubusercontent.com/example/a/HEAD/main.go"))
+	assert.NoError(t, get(t, limiter, "https://grep.app/api/search?q=foo"))
+	assert.Empty(t, clock.waits)
+	clock.advance(20 * time.Second)
+	assert.NoError(t, get(t, limiter, "https://api.github.com/repos/example/c"))
+	assert.Equal(t, []time.Duration{40 * time.Second}, clock.waits, "waits for the next token")
+	assert.NoError(t, get(t, limiter, "https://grep.app/api/search?q=foo&page=2"), "grep.app is never held back")
+	assert.Len(t, clock.waits, 1)
+	assert.Equal(t, []string{"api.github.com", "api.github.com", "raw.githubusercontent.com", "grep.app", "api.github.com", "grep.app"}, next.hosts)
+}
+
+func TestGithubLimiterSkipsWhenAsked(t *testing.T) {
+	next := &fakeTransport{header: http.Header{}}
+	clock := &fakeClock{t: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	limiter := grepgithub.NewGithubLimiter(next, 60, 1, &bytes.Buffer{})
+	limiter.SetClock(clock.now, clock.after)
+	skip := grepgithub.SkipGithubWait(context.Background())
+
+	assert.NoError(t, getContext(t, skip, limiter, "https://api.github.com/repos/example/a"))
+	assert.ErrorIs(t, getContext(t, skip, limiter, "https://api.github.com/repos/example/b"), grepgithub.ErrGithubLimited)
+	clock.advance(time.Minute)
+	assert.NoError(t, getContext(t, skip, limiter, "https://api.github.com/repos/example/b"))
+	assert.Empty(t, clock.waits)
+	assert.Equal(t, []string{"api.github.com", "api.github.com"}, next.hosts)
+}
+
+func TestGithubLimiterWaitHonorsContext(t *testing.T) {
+	next := &fakeTransport{header: http.Header{}}
+	limiter := grepgithub.NewGithubLimiter(next, 60, 1, &bytes.Buffer{})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	assert.NoError(t, getContext(t, ctx, limiter, "https://raw.githubusercontent.com/example/a/HEAD/a.go"))
+	cancel()
+	assert.ErrorIs(t, getContext(t, ctx, limiter, "https://raw.githubusercontent.com/example/a/HEAD/b.go"), context.Canceled)
+	assert.Len(t, next.hosts, 1)
+}
+
+func TestGithubLimiterPausesUntilReset(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	reset := clock.t.Add(10 * time.Minute)
+	next := &fakeTransport{header: http.Header{
+		"X-Ratelimit-Remaining": {"4"},
+		"X-Ratelimit-Reset":     {strconv.FormatInt(reset.Unix(), 10)},
+	}}
+	var warn bytes.Buffer
+	limiter := grepgithub.NewGithubLimiter(next, 5000, 5000, &warn)
+	limiter.SetClock(clock.now, clock.after)
+
+	assert.NoError(t, get(t, limiter, "https://api.github.com/repos/example/a"))
+	assert.Empty(t, warn.String(), "enough requests remain")
+	next.header.Set("X-Ratelimit-Remaining", "3")
+	assert.NoError(t, get(t, limiter, "https://api.github.com/repos/example/b"))
+	assert.Equal(t, "Warning: GitHub rate limit almost reached, pausing requests to api.github.com until "+reset.Local().Format(time.TimeOnly)+"\n", warn.String())
+	next.header.Del("X-Ratelimit-Remaining")
+	assert.NoError(t, get(t, limiter, "https://raw.githubusercontent.com/example/a/HEAD/main.go"), "other hosts are not paused")
+	assert.Empty(t, clock.waits)
+
+	clock.advance(time.Minute)
+	assert.ErrorIs(t, getContext(t, grepgithub.SkipGithubWait(context.Background()), limiter, "https://api.github.com/repos/example/c"), grepgithub.ErrGithubLimited)
+	assert.NoError(t, get(t, limiter, "https://api.github.com/repos/example/c"))
+	assert.Equal(t, []time.Duration{9 * time.Minute}, clock.waits)
+	assert.Equal(t, []string{"api.github.com", "api.github.com", "raw.githubusercontent.com", "api.github.com"}, next.hosts)
+}
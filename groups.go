@@ -0,0 +1,105 @@
+package main
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+)
+
+// group aggregates the matched files and lines sharing a key, such as their
+// repository.
+type group struct {
+	Name  string `json:"name"`
+	Files int    `json:"files"`
+	Lines int    `json:"lines"`
+}
+
+func byRepo(hit *grepapp.Result) string {
+	return hit.Repo
+}
+
+func byLang(hit *grepapp.Result) string {
+	if hit.Lang == "" {
+		return "unknown"
+	}
+	return hit.Lang
+}
+
+// groupBy aggregates hits by key, busiest group first. Only the first top
+// groups are kept unless top is 0.
+func groupBy(hits *grepapp.Results, key func(*grepapp.Result) string, top int) []group {
+	index := map[string]int{}
+	var groups []group
+	for i := range hits.Hits {
+		name := key(&hits.Hits[i])
+		j, ok := index[name]
+		if !ok {
+			j = len(groups)
+			index[name] = j
+			groups = append(groups, group{Name: name})
+		}
+		groups[j].Files++
+		groups[j].Lines += hits.Hits[i].MatchedLines()
+	}
+	slices.SortFunc(groups, func(a, b group) int {
+		return cmp.Or(cmp.Compare(b.Lines, a.Lines), cmp.Compare(b.Files, a.Files), cmp.Compare(a.Name, b.Name))
+	})
+	if top > 0 && len(groups) > top {
+		groups = groups[:top]
+	}
+	return groups
+}
+
+// writeGroups writes groups in the selected output format.
+func writeGroups(w io.Writer, groups []group, args *Arguments) error {
+	switch args.Format {
+	case "json":
+		if groups == nil {
+			groups = []group{}
+		}
+		enc := json.NewEncoder(w)
+		if args.Pretty {
+			enc.SetIndent("", "  ")
+		}
+		return enc.Encode(groups)
+	case "jsonl":
+		enc := json.NewEncoder(w)
+		for _, g := range groups {
+			if err := enc.Encode(g); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write([]string{"name", "files", "lines"}); err != nil {
+			return err
+		}
+		for _, g := range groups {
+			if err := cw.Write([]string{g.Name, strconv.Itoa(g.Files), strconv.Itoa(g.Lines)}); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, g := range groups {
+		name := g.Name
+		if !args.Monochrome {
+			name = C_PATH + name + grepapp.C_RST
+		}
+		if _, err := fmt.Fprintf(tw, "%s\t%d files\t%d lines\n", name, g.Files, g.Lines); err != nil {
+			return err
+		}
+	}
+	return tw.Flush()
+}
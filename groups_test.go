@@ -0,0 +1,57 @@
+package main_test
+
+import (
+	"bytes"
+	"testing"
+
+	grepgithub "github.com/aviadhahami/grepgithub-go"
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+	"github.com/stretchr/testify/assert"
+)
+
+func groupHits() *grepapp.Results {
+	hits := &grepapp.Results{}
+	hits.AddHit("small/repo", "main.go", 1, "foo")
+	hits.AddHit("busy/repo", "a.go", 1, "foo")
+	hits.AddHit("busy/repo", "a.go", 2, "foo")
+	hits.AddHit("busy/repo", "b.go", 5, "foo")
+	hits.AddHit("other/repo", "a.py", 1, "foo")
+	hits.AddHit("other/repo", "b.py", 1, "foo")
+	hits.Merge(&grepapp.Results{Hits: []grepapp.Result{{Repo: "small/repo", Path: "main.go", Lines: []grepapp.Line{{LineNumber: 2, Text: "context", Context: true}, {LineNumber: 3, Text: "context", Context: true}}}}})
+	return hits
+}
+
+func TestGroupByRepo(t *testing.T) {
+	groups := grepgithub.GroupBy(groupHits(), grepgithub.ByRepo, 0)
+
+	var buf bytes.Buffer
+	assert.NoError(t, grepgithub.WriteGroups(&buf, groups, &grepgithub.Arguments{Format: "text", Monochrome: true}))
+	assert.Equal(t, "busy/repo   2 files  3 lines\n"+
+		"other/repo  2 files  2 lines\n"+
+		"small/repo  1 files  1 lines\n", buf.String())
+}
+
+func TestGroupByRepoTop(t *testing.T) {
+	groups := grepgithub.GroupBy(groupHits(), grepgithub.ByRepo, 1)
+
+	var buf bytes.Buffer
+	assert.NoError(t, grepgithub.WriteGroups(&buf, groups, &grepgithub.Arguments{Format: "json"}))
+	assert.Equal(t, `[{"name":"busy/repo","files":2,"lines":3}]`+"\n", buf.String())
+}
+
+func TestGroupByLang(t *testing.T) {
+	hits := groupHits()
+	hits.Merge(&grepapp.Results{Hits: []grepapp.Result{
+		{Repo: "busy/repo", Path: "a.go", Lang: "Go"},
+		{Repo: "busy/repo", Path: "b.go", Lang: "Go"},
+		{Repo: "small/repo", Path: "main.go", Lang: "Go"},
+	}})
+
+	groups := grepgithub.GroupBy(hits, grepgithub.ByLang, 0)
+
+	var buf bytes.Buffer
+	assert.NoError(t, grepgithub.WriteGroups(&buf, groups, &grepgithub.Arguments{Format: "csv"}))
+	assert.Equal(t, "name,files,lines\n"+
+		"Go,3,4\n"+
+		"unknown,2,2\n", buf.String())
+}
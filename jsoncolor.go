@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+)
+
+// Colors of -color-json output.
+const (
+	C_KEY     = "\033[34m"
+	C_STRING  = "\033[32m"
+	C_NUMBER  = "\033[36m"
+	C_LITERAL = "\033[35m"
+)
+
+// colorJSON highlights the keys, strings, numbers and literals of data,
+// which must be JSON as encoding/json writes it. Only escape sequences are
+// added: encoding/json escapes control characters within strings, so
+// stripping them gives data back unchanged.
+func colorJSON(data []byte) []byte {
+	var b bytes.Buffer
+	token := func(color string, text []byte) {
+		b.WriteString(color)
+		b.Write(text)
+		b.WriteString(grepapp.C_RST)
+	}
+	for i := 0; i < len(data); {
+		c := data[i]
+		switch {
+		case c == '"':
+			end := i + 1
+			for end < len(data) && data[end] != '"' {
+				if data[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end = min(end+1, len(data))
+			color := C_STRING
+			if rest := bytes.TrimLeft(data[end:], " \t\r\n"); len(rest) > 0 && rest[0] == ':' {
+				color = C_KEY
+			}
+			token(color, data[i:end])
+			i = end
+		case c == '-' || c >= '0' && c <= '9':
+			end := i + 1
+			for end < len(data) && strings.IndexByte("+-.eE0123456789", data[end]) >= 0 {
+				end++
+			}
+			token(C_NUMBER, data[i:end])
+			i = end
+		case c >= 'a' && c <= 'z':
+			end := i + 1
+			for end < len(data) && data[end] >= 'a' && data[end] <= 'z' {
+				end++
+			}
+			token(C_LITERAL, data[i:end])
+			i = end
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.Bytes()
+}
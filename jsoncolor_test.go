@@ -0,0 +1,54 @@
+package main_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"testing"
+
+	grepgithub "github.com/aviadhahami/grepgithub-go"
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+	"github.com/stretchr/testify/assert"
+)
+
+var sgr = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+func TestColorJSON(t *testing.T) {
+	data, err := json.MarshalIndent(map[string]any{
+		"count":  -1.5e3,
+		"key":    `say "a": \b` + "\x1b[31m",
+		"ok":     true,
+		"absent": nil,
+		"lines":  []any{3, "x"},
+	}, "", "  ")
+	assert.NoError(t, err)
+
+	colored := grepgithub.ColorJSON(data)
+	assert.Equal(t, string(data), sgr.ReplaceAllString(string(colored), ""), "only escape sequences are added")
+	rst := grepapp.C_RST
+	for _, token := range []string{
+		grepgithub.C_KEY + `"count"` + rst + ": " + grepgithub.C_NUMBER + "-1500" + rst,
+		grepgithub.C_KEY + `"key"` + rst + ": " + grepgithub.C_STRING + `"say \"a\": \\b\u001b[31m"` + rst,
+		grepgithub.C_LITERAL + "true" + rst,
+		grepgithub.C_LITERAL + "null" + rst,
+		grepgithub.C_NUMBER + "3" + rst + ",\n    " + grepgithub.C_STRING + `"x"` + rst,
+	} {
+		assert.Contains(t, string(colored), token)
+	}
+}
+
+func TestWriteJSONColor(t *testing.T) {
+	hits := &grepapp.Results{}
+	hits.AddHit("example/repo", "main.go", 3, "foo")
+	args, err := grepgithub.ParseArguments([]string{"-q", "foo", "-json", "-pretty", "-color-json"})
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, grepgithub.WriteJSON(&buf, hits, args))
+	assert.Contains(t, buf.String(), grepgithub.C_KEY+`"repo"`)
+
+	args.Monochrome = true
+	buf.Reset()
+	assert.NoError(t, grepgithub.WriteJSON(&buf, hits, args))
+	assert.NotContains(t, buf.String(), "\x1b")
+}
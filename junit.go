@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string       `xml:"classname,attr"`
+	Name      string       `xml:"name,attr"`
+	Failure   junitFailure `xml:"failure"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit writes hits as a JUnit XML report for CI dashboards, with
+// each matched file as a failed test case of a suite named after the query.
+// The failure lists the file's matched lines as repo/path:line: text.
+func writeJUnit(w io.Writer, hits *grepapp.Results, args *Arguments) error {
+	suite := junitTestSuite{
+		Name:  strings.Join(args.queries(), ","),
+		Cases: []junitTestCase{},
+	}
+	for _, hit := range hits.Hits {
+		location := hit.Repo + "/" + hit.Path
+		var lines []string
+		for _, line := range hit.Lines {
+			if !line.Context {
+				lines = append(lines, fmt.Sprintf("%s:%d: %s", location, line.LineNumber, stripANSI(line.Text)))
+			}
+		}
+		message := "Matched " + location
+		if len(lines) > 0 {
+			message = fmt.Sprintf("%d matched lines in %s", len(lines), location)
+		}
+		suite.Cases = append(suite.Cases, junitTestCase{
+			ClassName: hit.Repo,
+			Name:      hit.Path,
+			Failure:   junitFailure{Message: message, Type: "match", Text: strings.Join(lines, "\n")},
+		})
+	}
+	suite.Tests, suite.Failures = len(suite.Cases), len(suite.Cases)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitTestSuites{Name: "grepgithub", Tests: suite.Tests, Failures: suite.Failures, Suites: []junitTestSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,34 @@
+package main_test
+
+import (
+	"bytes"
+	"testing"
+
+	grepgithub "github.com/aviadhahami/grepgithub-go"
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteJUnit(t *testing.T) {
+	hits := &grepapp.Results{}
+	hits.AddHit("example/repo", "main.go", 3, "foo("+grepapp.C_MARK+"x"+grepapp.C_RST+") < y")
+	hits.AddHit("example/repo", "main.go", 9, "foo")
+	hits.Merge(&grepapp.Results{Hits: []grepapp.Result{{Repo: "example/repo", Path: "README.md"}}})
+	args := &grepgithub.Arguments{}
+	args.Query = "foo"
+
+	var buf bytes.Buffer
+	assert.NoError(t, grepgithub.WriteJUnit(&buf, hits, args))
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="grepgithub" tests="2" failures="2">
+  <testsuite name="foo" tests="2" failures="2">
+    <testcase classname="example/repo" name="main.go">
+      <failure message="2 matched lines in example/repo/main.go" type="match">example/repo/main.go:3: foo(x) &lt; y&#xA;example/repo/main.go:9: foo</failure>
+    </testcase>
+    <testcase classname="example/repo" name="README.md">
+      <failure message="Matched example/repo/README.md" type="match"></failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`, buf.String())
+}
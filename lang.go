@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+)
+
+// languageAliases maps common nicknames that are too far from the language
+// name for an edit distance to find it.
+var languageAliases = map[string]string{
+	"golang":     "Go",
+	"js":         "JavaScript",
+	"node":       "JavaScript",
+	"ts":         "TypeScript",
+	"py":         "Python",
+	"python3":    "Python",
+	"rb":         "Ruby",
+	"rs":         "Rust",
+	"cpp":        "C++",
+	"csharp":     "C#",
+	"cs":         "C#",
+	"fsharp":     "F#",
+	"bash":       "Shell",
+	"sh":         "Shell",
+	"zsh":        "Shell",
+	"yml":        "YAML",
+	"md":         "Markdown",
+	"objc":       "Objective-C",
+	"terraform":  "HCL",
+	"protobuf":   "Protocol Buffer",
+	"dockerfile": "Dockerfile",
+}
+
+// unknownLanguages returns a message for every language in the comma
+// separated value that grep.app does not know, suggesting the closest known
+// one when there is a likely candidate.
+func unknownLanguages(value string) []string {
+	var msgs []string
+	for _, lang := range strings.Split(value, ",") {
+		lang = strings.TrimSpace(lang)
+		if lang == "" || slices.Contains(grepapp.Languages, lang) {
+			continue
+		}
+		msg := fmt.Sprintf("Unknown language %q", lang)
+		if suggestion := suggestLanguage(lang); suggestion != "" {
+			msg += fmt.Sprintf(", did you mean %q?", suggestion)
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
+
+func suggestLanguage(lang string) string {
+	lower := strings.ToLower(lang)
+	if alias, ok := languageAliases[lower]; ok {
+		return alias
+	}
+	best, bestDistance := "", len(lower)/2+1
+	for _, known := range grepapp.Languages {
+		if d := levenshtein(lower, strings.ToLower(known)); d < bestDistance {
+			best, bestDistance = known, d
+		}
+	}
+	return best
+}
+
+// levenshtein returns the number of single character edits needed to turn
+// a into b.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,24 @@
+package main_test
+
+import (
+	"testing"
+
+	grepgithub "github.com/aviadhahami/grepgithub-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnknownLanguages(t *testing.T) {
+	assert.Empty(t, grepgithub.UnknownLanguages("Go,Python, C++"))
+	assert.Equal(t, []string{
+		`Unknown language "Golang", did you mean "Go"?`,
+		`Unknown language "Pyhton", did you mean "Python"?`,
+		`Unknown language "typescript", did you mean "TypeScript"?`,
+		`Unknown language "Brainfudge"`,
+	}, grepgithub.UnknownLanguages("Golang,Pyhton,Java,typescript,Brainfudge"))
+}
+
+func TestLevenshtein(t *testing.T) {
+	assert.Equal(t, 0, grepgithub.Levenshtein("go", "go"))
+	assert.Equal(t, 3, grepgithub.Levenshtein("kitten", "sitting"))
+	assert.Equal(t, 2, grepgithub.Levenshtein("", "go"))
+}
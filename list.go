@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+)
+
+// distinct returns the sorted, de-duplicated keys of hits, leaving out
+// empty ones.
+func distinct(hits *grepapp.Results, key func(*grepapp.Result) string) []string {
+	items := []string{}
+	for i := range hits.Hits {
+		if item := key(&hits.Hits[i]); item != "" {
+			items = append(items, item)
+		}
+	}
+	slices.Sort(items)
+	return slices.Compact(items)
+}
+
+// writeList writes items one per line, or as strings in the JSON and CSV
+// formats, with name as the CSV header.
+func writeList(w io.Writer, name string, items []string, args *Arguments) error {
+	switch args.Format {
+	case "json":
+		enc := json.NewEncoder(w)
+		if args.Pretty {
+			enc.SetIndent("", "  ")
+		}
+		return enc.Encode(items)
+	case "jsonl":
+		enc := json.NewEncoder(w)
+		for _, item := range items {
+			if err := enc.Encode(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write([]string{name}); err != nil {
+			return err
+		}
+		for _, item := range items {
+			if err := cw.Write([]string{item}); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	for _, item := range items {
+		if _, err := fmt.Fprintln(w, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package main_test
+
+import (
+	"bytes"
+	"testing"
+
+	grepgithub "github.com/aviadhahami/grepgithub-go"
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReposOnly(t *testing.T) {
+	hits := &grepapp.Results{}
+	hits.AddHit("b/repo", "main.go", 1, "foo")
+	hits.AddHit("a/repo", "main.go", 1, "foo")
+	hits.AddHit("b/repo", "lib.go", 1, "foo")
+	repos := grepgithub.Distinct(hits, grepgithub.ByRepo)
+	assert.Equal(t, []string{"a/repo", "b/repo"}, repos)
+
+	var buf bytes.Buffer
+	assert.NoError(t, grepgithub.WriteList(&buf, "repo", repos, &grepgithub.Arguments{Format: "text"}))
+	assert.Equal(t, "a/repo\nb/repo\n", buf.String())
+
+	buf.Reset()
+	assert.NoError(t, grepgithub.WriteList(&buf, "repo", repos, &grepgithub.Arguments{Format: "json"}))
+	assert.Equal(t, `["a/repo","b/repo"]`+"\n", buf.String())
+
+	buf.Reset()
+	assert.NoError(t, grepgithub.WriteList(&buf, "repo", []string{}, &grepgithub.Arguments{Format: "json"}))
+	assert.Equal(t, "[]\n", buf.String())
+}
+
+func TestPathsOnly(t *testing.T) {
+	hits := &grepapp.Results{}
+	hits.AddHit("b/repo", "main.go", 1, "foo")
+	hits.AddHit("a/repo", "main.go", 1, "foo")
+	hits.AddHit("b/repo", "lib.go", 1, "foo")
+
+	args, err := grepgithub.ParseArguments([]string{"-q", "foo", "-paths-only"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a/repo/main.go", "b/repo/lib.go", "b/repo/main.go"}, grepgithub.Distinct(hits, args.ListKey()))
+
+	args, err = grepgithub.ParseArguments([]string{"-q", "foo", "-paths-only", "-strip-repo"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"lib.go", "main.go"}, grepgithub.Distinct(hits, args.ListKey()))
+}
+
+func TestCloneURLs(t *testing.T) {
+	hits := &grepapp.Results{}
+	hits.AddHit("b/repo", "main.go", 1, "foo")
+	hits.AddHit("a/repo", "main.go", 1, "foo")
+	hits.AddHit("b/repo", "lib.go", 1, "foo")
+	hits.AddHit("gitlab.com/group/repo", "main.go", 1, "foo")
+
+	args, err := grepgithub.ParseArguments([]string{"-q", "foo", "-clone-urls"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://github.com/a/repo.git", "https://github.com/b/repo.git"}, grepgithub.Distinct(hits, args.ListKey()))
+}
@@ -1,192 +1,1084 @@
 package main
 
 import (
-	"encoding/json"
+	"bufio"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"log"
-	"net/http"
+	"log/slog"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"syscall"
+	"text/template"
 	"time"
+
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
 )
 
 const (
-	C_MARK = "\033[32m"
-	C_RST  = "\033[0m"
+	exitMatches   = 0
+	exitNoMatches = 1
+	exitError     = 2
+	exitPartial   = 3
+	exitTimeout   = 4
 )
 
-type Hit struct {
-	Repo  string            `json:"repo"`
-	Path  string            `json:"path"`
-	Lines map[string]string `json:"lines"`
-}
+var version = "dev"
 
-type Hits struct {
-	Hits []Hit `json:"hits"`
+// maxHead is the most pages -head fetches, small enough to skip the delay
+// between them.
+const maxHead = 5
+
+func fail(errorMsg string) {
+	log.Printf("Error: %s", errorMsg)
+	os.Exit(exitError)
 }
 
-func (h *Hits) AddHit(repo, path, lineNum, line string) {
-	for i := range h.Hits {
-		hit := &h.Hits[i]
-		if hit.Repo == repo && hit.Path == path {
-			hit.Lines[lineNum] = line
-			return
+func newClient(args *Arguments) *grepapp.Client {
+	client := grepapp.NewClient()
+	client.BaseURL = args.BaseURL
+	client.UserAgent = "grepgithub-go/" + version
+	client.PageDelay = args.PageDelay
+	client.MaxPageDelay = args.MaxPageDelay
+	client.Concurrency = args.Concurrency
+	client.Retries = args.Retries
+	client.RetryBase = args.RetryBase
+	client.SkipFailedPages = !args.Strict
+	if args.Proxy != nil {
+		client.HTTPClient = grepapp.NewHTTPClient(args.Proxy)
+	}
+	rate := args.GithubRate
+	if rate == 0 {
+		rate = githubRate
+		if args.GithubToken != "" {
+			rate = githubTokenRate
 		}
 	}
-	h.Hits = append(h.Hits, Hit{
-		Repo:  repo,
-		Path:  path,
-		Lines: map[string]string{lineNum: line},
-	})
-}
-
-func (h *Hits) Merge(hits2 *Hits) {
-	for _, hit2 := range hits2.Hits {
-		h.AddHit(hit2.Repo, hit2.Path, "", "")
-		for lineNum, line := range hit2.Lines {
-			h.AddHit(hit2.Repo, hit2.Path, lineNum, line)
+	// Copied so that the shared default client is left alone
+	httpClient := *client.HTTPClient
+	httpClient.Transport = newGithubLimiter(httpClient.Transport, rate, rate, warnWriter(args))
+	client.HTTPClient = &httpClient
+	if args.Verbose > 0 {
+		level := slog.LevelInfo
+		if args.Verbose > 1 {
+			level = slog.LevelDebug
 		}
+		client.Logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
 	}
+	if args.CacheDir != "" {
+		client.Cache = grepapp.NewCache(args.CacheDir, args.CacheTTL)
+		client.Cache.Refresh = args.NoCache
+	}
+	return client
 }
 
-func fail(errorMsg string) {
-	log.Fatalf("Error: %s", errorMsg)
+// partialError reports the pages that could not be fetched while the rest
+// of the scan went on.
+type partialError struct {
+	pages []*grepapp.Page
 }
 
-func fetchGrepApp(page int, args *Arguments) (*Hits, int, error) {
-	query := args.Query
-	url := fmt.Sprintf("https://grep.app/api/search?q=%s&page=%d", query, page)
+func (e *partialError) Error() string {
+	msgs := make([]string, len(e.pages))
+	for i, page := range e.pages {
+		msgs[i] = fmt.Sprintf("page %d: %s", page.Number, page.Err)
+	}
+	return fmt.Sprintf("%d pages failed, results are incomplete: %s", len(e.pages), strings.Join(msgs, "; "))
+}
 
-	if args.UseRegex {
-		url += "&regexp=true"
-	} else if args.WholeWords {
-		url += "&words=true"
+func (e *partialError) Unwrap() []error {
+	errs := make([]error, len(e.pages))
+	for i, page := range e.pages {
+		errs[i] = page.Err
 	}
+	return errs
+}
 
-	if args.CaseSensitive {
-		url += "&case=true"
+// errorMessage returns the message main prints for err, suggesting a longer
+// delay when grep.app kept rate limiting requests.
+func errorMessage(err error) string {
+	if errors.Is(err, grepapp.ErrRateLimited) {
+		return err.Error() + ". Try a longer -delay, or a -max-delay to slow down as needed"
 	}
-	if args.RepoFilter != "" {
-		url += fmt.Sprintf("&f.repo.pattern=%s", args.RepoFilter)
+	return err.Error()
+}
+
+// search runs the scan, handing each filtered page to onPage, and returns
+// the merged hits along with the number of files matched. With -stream,
+// pages are only handed to onPage and the hits stay empty.
+func search(ctx context.Context, client *grepapp.Client, args *Arguments, prog *progress, onPage func(*grepapp.Results) error) (*grepapp.Results, int, error) {
+	// An empty slice rather than nil, so that JSON shows no matches as []
+	hits := &grepapp.Results{Hits: []grepapp.Result{}, MaxLines: args.MaxLinesPerFile, ContextAfter: args.ContextAfter}
+	partial := &partialError{}
+	files := 0
+	var seen *seenSet
+	if args.Stream {
+		seen = newSeenSet(streamMemory)
+	}
+	var st *stars
+	if args.WithStars {
+		st = newStars(client, args.GithubToken, warnWriter(args))
+	}
+	var cp *checkpoint
+	if args.Checkpoint != "" {
+		var err error
+		if cp, err = loadCheckpoint(args.Checkpoint, args); err != nil {
+			return hits, files, err
+		}
+	}
+	if cp != nil {
+		if !args.Quiet {
+			fmt.Fprintf(os.Stderr, "Resuming from page %d with %d files\n", cp.Page+1, len(cp.Hits))
+		}
+		hits.Merge(&grepapp.Results{Hits: cp.Hits})
+		hits.Count = cp.Count
+		files = len(hits.Hits)
+		if err := onPage(&grepapp.Results{Hits: cp.Hits}); err != nil {
+			return hits, files, err
+		}
+	} else {
+		cp = &checkpoint{Query: queryOf(args), PerPage: args.PerPage}
+	}
+	// -any runs one search per term, one after the other so that the page
+	// delay keeps applying between them
+	var err error
+	limitReached := false
+	for i, term := range args.queries() {
+		if i < cp.Term {
+			continue
+		}
+		opts := args.SearchOptions
+		opts.Query = term
+		if i == cp.Term {
+			opts.FirstPage = cp.Page + 1
+		}
+		err = client.Walk(ctx, &opts, func(page *grepapp.Page) error {
+			if page.Err != nil {
+				partial.pages = append(partial.pages, page)
+				return nil
+			}
+			prog.estimate(page)
+			// With -any, files matching several terms are counted once per term
+			if page.Number == 1 {
+				hits.Count += page.Count
+			}
+			exclude(page.Results, args.RepoExclude, args.PathExclude)
+			keepExtensions(page.Results, args.Extensions)
+			if args.FilterText != "" {
+				filterLines(page.Results, containsText(args.FilterText, args.CaseSensitive), args.ContextBefore, args.ContextAfter)
+			}
+			if args.TextExclude != nil {
+				filterLines(page.Results, excludingText(args.TextExclude), args.ContextBefore, args.ContextAfter)
+			}
+			page.Results.LimitLines(args.MaxLinesPerFile)
+			if args.FirstLineOnly {
+				firstLineOnly(page.Results)
+			}
+			if len(args.Any) > 0 {
+				for i := range page.Results.Hits {
+					page.Results.Hits[i].Terms = []string{term}
+				}
+			}
+			if st != nil {
+				if err := st.annotate(ctx, page.Results); err != nil {
+					return err
+				}
+			}
+			if seen != nil {
+				limitReached = seen.dedup(page.Results, &files, args.Limit)
+			} else {
+				limitReached = limit(page.Results, hits, args.Limit)
+				hits.Merge(page.Results)
+				files = len(hits.Hits)
+			}
+			prog.clear()
+			if err := onPage(page.Results); err != nil {
+				return err
+			}
+			prog.update(page.Number, files)
+			if args.Checkpoint != "" {
+				// Failed pages are fetched again on resume, along with the pages after them
+				if len(partial.pages) == 0 {
+					cp.Term, cp.Page = i, page.Number
+				}
+				cp.Count, cp.Hits = hits.Count, hits.Hits
+				if err := cp.save(args.Checkpoint); err != nil {
+					return err
+				}
+			}
+			if limitReached {
+				return grepapp.ErrStop
+			}
+			return nil
+		})
+		if err != nil || limitReached {
+			break
+		}
 	}
-	if args.PathFilter != "" {
-		url += fmt.Sprintf("&f.path.pattern=%s", args.PathFilter)
+	prog.clear()
+	if err == nil && len(partial.pages) > 0 {
+		err = partial
 	}
-	if args.LangFilter != "" {
-		url += fmt.Sprintf("&f.lang=%s", args.LangFilter)
+	// A complete scan has nothing left to resume
+	if err == nil && args.Checkpoint != "" {
+		if removeErr := os.Remove(args.Checkpoint); removeErr != nil && !errors.Is(removeErr, fs.ErrNotExist) {
+			err = fmt.Errorf("Cannot remove checkpoint: %w", removeErr)
+		}
 	}
+	return hits, files, err
+}
 
-	resp, err := http.Get(url)
+func printCount(ctx context.Context, client *grepapp.Client, out io.Writer, args *Arguments) error {
+	_, count, err := client.Page(ctx, &args.SearchOptions, 1)
 	if err != nil {
-		return nil, 0, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, 0, fmt.Errorf("HTTP %d %s", resp.StatusCode, url)
-	}
-
-	var data struct {
-		Facets struct {
-			Count int `json:"count"`
-		} `json:"facets"`
-		Hits struct {
-			Hits []struct {
-				Repo struct {
-					Raw string `json:"raw"`
-				} `json:"repo"`
-				Path struct {
-					Raw string `json:"raw"`
-				} `json:"path"`
-				Content struct {
-					Snippet string `json:"snippet"`
-				} `json:"content"`
-			} `json:"hits"`
-		} `json:"hits"`
-	}
-
-	err = json.NewDecoder(resp.Body).Decode(&data)
-	if err != nil {
-		return nil, 0, err
+		return err
 	}
+	_, err = fmt.Fprintln(out, count)
+	return err
+}
 
-	hits := &Hits{}
-	for _, hitData := range data.Hits.Hits {
-		repo := hitData.Repo.Raw
-		path := hitData.Path.Raw
-		snippet := hitData.Content.Snippet
-		hits.AddHit(repo, path, "", "")
-		lines := strings.Split(snippet, "\n")
-		for _, line := range lines {
-			if strings.Contains(line, "<mark") {
-				line = strings.ReplaceAll(line, "<mark", C_MARK)
-				line = strings.ReplaceAll(line, "</mark>", C_RST)
-				line = regexp.MustCompile(`<[^>]*>`).ReplaceAllString(line, "")
-				line = strings.ReplaceAll(line, C_MARK, C_RST+C_MARK)
-				hits.AddHit(repo, path, line, line)
+// printURLs writes the URL of every page a search could request, up to
+// -max-pages, without requesting any.
+func printURLs(client *grepapp.Client, out io.Writer, args *Arguments) error {
+	for _, query := range args.queries() {
+		opts := args.SearchOptions
+		opts.Query = query
+		for page := 1; page <= min(max(args.MaxPages, 1), grepapp.MaxPages); page++ {
+			if _, err := fmt.Fprintln(out, client.PageURL(&opts, page)); err != nil {
+				return err
 			}
 		}
 	}
+	return nil
+}
+
+// printResults writes the search results in the selected format and returns
+// them, along with the number of files matched.
+func printResults(ctx context.Context, client *grepapp.Client, out *bufio.Writer, args *Arguments, prog *progress) (*grepapp.Results, int, error) {
+	// Groups, sorted output and repositories with enough matches are only
+	// known once the scan is complete
+	streaming := streamingFormats[args.Format] && len(args.Sort) == 0 && args.MinMatches == 0 && args.MaxFilesPerRepo == 0 && !args.ByRepo && !args.ByLang && !args.listing() && !args.TUI && args.Diff == nil && !args.CountLines
+	written := false
+	hits, files, err := search(ctx, client, args, prog, func(page *grepapp.Results) error {
+		if !streaming || len(page.Hits) == 0 {
+			return nil
+		}
+		// Colored text separates files with a blank line, pages too
+		if written && args.Format == "text" && !args.Monochrome && args.Template == nil && args.Fields == nil {
+			if err := out.WriteByte('\n'); err != nil {
+				return err
+			}
+		}
+		written = true
+		if err := render(out, page, args); err != nil {
+			return err
+		}
+		return out.Flush()
+	})
+	// Whatever was gathered before a cancellation, a timeout or failed pages is
+	// still printed
+	var partial *partialError
+	switch {
+	case errors.Is(err, context.Canceled):
+		err = nil
+	case errors.Is(err, context.DeadlineExceeded):
+	case err != nil && !errors.As(err, &partial):
+		return hits, files, err
+	}
+
+	// A file found on several pages may have gained lines before its first
+	if args.FirstLineOnly {
+		firstLineOnly(hits)
+	}
+	if args.MinMatches > 0 {
+		minMatches(hits, args.MinMatches)
+		files = len(hits.Hits)
+	}
+	if args.MaxFilesPerRepo > 0 {
+		maxFilesPerRepo(hits, args.MaxFilesPerRepo)
+		files = len(hits.Hits)
+	}
+	sortHits(hits, args.Sort)
+	var renderErr error
+	switch {
+	case args.listing() && args.Count:
+		_, renderErr = fmt.Fprintln(out, len(distinct(hits, args.listKey())))
+	case args.CountLines:
+		_, renderErr = fmt.Fprintln(out, matchedLines(hits))
+	case args.ReposOnly:
+		renderErr = writeList(out, "repo", distinct(hits, args.listKey()), args)
+	case args.PathsOnly:
+		renderErr = writeList(out, "path", distinct(hits, args.listKey()), args)
+	case args.CloneURLs:
+		renderErr = writeList(out, "url", distinct(hits, args.listKey()), args)
+	case args.ByRepo:
+		renderErr = writeGroups(out, groupBy(hits, byRepo, args.Top), args)
+	case args.ByLang:
+		renderErr = writeGroups(out, groupBy(hits, byLang, args.Top), args)
+	case args.TUI:
+		if len(hits.Hits) > 0 {
+			renderErr = browse(hits, args)
+		}
+	case args.Diff != nil:
+		added, removed := diffHits(args.Diff, hits)
+		sortHits(added, args.Sort)
+		sortHits(removed, args.Sort)
+		renderErr = writeDiff(out, added, removed, args)
+	case !streaming:
+		renderErr = render(out, hits, args)
+	}
+	if renderErr != nil {
+		return hits, files, renderErr
+	}
+	return hits, files, err
+}
+
+// verbosity is a boolean flag that counts how many times it is given, so
+// that -v -v is more verbose than -v. It also accepts a level, as in -v=2.
+type verbosity int
 
-	count := data.Facets.Count
-	return hits, count, nil
+func (v *verbosity) String() string {
+	return strconv.Itoa(int(*v))
+}
+
+func (v *verbosity) Set(value string) error {
+	if b, err := strconv.ParseBool(value); err == nil {
+		if b {
+			*v++
+		} else {
+			*v = 0
+		}
+		return nil
+	}
+	level, err := strconv.Atoi(value)
+	if err != nil || level < 0 {
+		return errors.New("expected a boolean or a level")
+	}
+	*v = verbosity(level)
+	return nil
+}
+
+func (v *verbosity) IsBoolFlag() bool {
+	return true
+}
+
+// errNoMatches is returned by run when a search completes without matching
+// any file.
+var errNoMatches = errors.New("no matches")
+
+// errTimeout is returned by run when -timeout cut the scan short.
+var errTimeout = errors.New("Timed out, results are incomplete")
+
+// warnWriter returns where warnings go: stderr, unless -quiet discards them.
+func warnWriter(args *Arguments) io.Writer {
+	if args.Quiet {
+		return io.Discard
+	}
+	return os.Stderr
+}
+
+// run performs the search described by args and writes its output to out.
+// Output produced before an error is still written.
+func run(ctx context.Context, args *Arguments, out io.Writer) (err error) {
+	if args.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, args.Timeout)
+		defer cancel()
+		defer func() {
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				err = errTimeout
+			}
+		}()
+	}
+	client := newClient(args)
+	w := bufio.NewWriter(out)
+	defer func() {
+		if flushErr := w.Flush(); flushErr != nil && (err == nil || errors.Is(err, errNoMatches)) {
+			err = flushErr
+		}
+	}()
+
+	start := time.Now()
+	args.Started = start
+	switch {
+	case args.DryRun:
+		return printURLs(client, w, args)
+	case args.Count && !args.listing():
+		return printCount(ctx, client, w, args)
+	case args.Watch > 0:
+		return watch(ctx, client, w, args, warnWriter(args))
+	}
+	hits, files, err := printResults(ctx, client, w, args, newProgress(args))
+	if args.Download != "" && ctx.Err() == nil {
+		w.Flush()
+		// Like the search, downloads interrupted by the user are not an error
+		downloadErr := downloadFiles(ctx, client, hits, args.Download, warnWriter(args))
+		if err == nil && !errors.Is(downloadErr, context.Canceled) {
+			err = downloadErr
+		}
+	}
+	if args.SQLite != "" {
+		// Like the output, whatever was gathered before an interruption is kept
+		sqliteErr := writeSQLite(context.WithoutCancel(ctx), args.SQLite, hits, args)
+		if err == nil {
+			err = sqliteErr
+		}
+	}
+	if args.Copy != "" && len(hits.Hits) > 0 {
+		w.Flush()
+		copyResult(&hits.Hits[0], args)
+	}
+	if args.Open && ctx.Err() == nil {
+		w.Flush()
+		openFiles(hits, openURL, warnWriter(args))
+	}
+	if args.Stats && !args.Quiet {
+		w.Flush()
+		writeStats(os.Stderr, hits, time.Since(start))
+	}
+	if err != nil {
+		return err
+	}
+	if files == 0 {
+		return errNoMatches
+	}
+	return nil
 }
 
 type Arguments struct {
-	Query         string
-	CaseSensitive bool
-	UseRegex      bool
-	WholeWords    bool
-	RepoFilter    string
-	PathFilter    string
-	LangFilter    string
-	JsonOutput    bool
-	Monochrome    bool
+	grepapp.SearchOptions
+	RepoExclude     *regexp.Regexp
+	PathExclude     *regexp.Regexp
+	Extensions      []string
+	FilterText      string
+	TextExclude     *regexp.Regexp
+	WithStars       bool
+	GithubToken     string
+	GithubRate      int
+	Format          string
+	JsonOutput      bool
+	JsonlOutput     bool
+	CsvOutput       bool
+	Count           bool
+	CountLines      bool
+	Stats           bool
+	DryRun          bool
+	ByRepo          bool
+	ByLang          bool
+	ReposOnly       bool
+	PathsOnly       bool
+	CloneURLs       bool
+	StripRepo       bool
+	Top             int
+	Limit           int
+	Stream          bool
+	Checkpoint      string
+	MinMatches      int
+	MaxFilesPerRepo int
+	MaxLinesPerFile int
+	FirstLineOnly   bool
+	Pretty          bool
+	ColorJSON       bool
+	Links           bool
+	Fields          []string
+	Sort            []string
+	Template        *template.Template
+	Any             []string
+	TUI             bool
+	Copy            string
+	Download        string
+	SQLite          string
+	Watch           time.Duration
+	ExitOnNew       bool
+	Webhook         string
+	WebhookSecret   string
+	Open            bool
+	OutputFile      string
+	Monochrome      bool
+	Color           string
+	MatchColor      string
+	PageDelay       time.Duration
+	MaxPageDelay    time.Duration
+	NoSleep         bool
+	Head            int
+	Timeout         time.Duration
+	Concurrency     int
+	Retries         int
+	RetryBase       time.Duration
+	Strict          bool
+	BaseURL         string
+	Proxy           *url.URL
+	Cache           bool
+	CacheTTL        time.Duration
+	NoCache         bool
+	CacheDir        string
+	Verbose         verbosity
+	Quiet           bool
+	// Diff holds the hits of the previous -json output given to -diff.
+	Diff *grepapp.Results
+	// Started is when the search began, reported in -json output.
+	Started time.Time
 }
 
-func parseArguments() *Arguments {
-	args := &Arguments{}
-	flag.StringVar(&args.Query, "q", "", "Query string, required")
-	flag.BoolVar(&args.CaseSensitive, "c", false, "Case sensitive search")
-	flag.BoolVar(&args.UseRegex, "r", false, "Use regex query. Cannot be used with -w")
-	flag.BoolVar(&args.WholeWords, "w", false, "Search whole words. Cannot be used with -r")
-	flag.StringVar(&args.RepoFilter, "frepo", "", "Filter repository")
-	flag.StringVar(&args.PathFilter, "fpath", "", "Filter path")
-	flag.StringVar(&args.LangFilter, "flang", "", "Filter language (eg. Python,C,Java). Use comma for multiple values")
-	flag.BoolVar(&args.JsonOutput, "json", false, "JSON output")
-	flag.BoolVar(&args.Monochrome, "m", false, "Monochrome output")
-	flag.Parse()
+// listing reports whether only the distinct repositories, paths or clone
+// URLs are printed.
+func (args *Arguments) listing() bool {
+	return args.ReposOnly || args.PathsOnly || args.CloneURLs
+}
 
-	if args.Query == "" {
-		fail("Query string is required")
+// queries returns the terms given to -any, or else the -q query.
+func (args *Arguments) queries() []string {
+	if len(args.Any) > 0 {
+		return args.Any
 	}
+	return []string{args.Query}
+}
 
-	return args
+// listKey returns what -repos-only, -paths-only or -clone-urls list for each
+// file.
+func (args *Arguments) listKey() func(*grepapp.Result) string {
+	switch {
+	case args.ReposOnly:
+		return byRepo
+	case args.CloneURLs:
+		return func(hit *grepapp.Result) string { return grepapp.CloneURL(hit.Repo) }
+	case args.StripRepo:
+		return func(hit *grepapp.Result) string { return hit.Path }
+	}
+	return func(hit *grepapp.Result) string { return hit.Repo + "/" + hit.Path }
 }
 
-func main() {
-	args := parseArguments()
+// commands lists the subcommands with their description. The first one runs
+// when the first argument is a flag, as it did before subcommands existed.
+var commands = []struct {
+	name, description string
+}{
+	{"search", "Search grep.app and print the matching files and lines"},
+	{"count", "Print the number of matching files reported by grep.app, same as search -count"},
+	{"open", "Open the first matching file in the browser, same as search -open"},
+}
 
-	if !args.JsonOutput {
-		log.Fatal("JSONL output is required")
+// splitCommand returns the subcommand named by the first argument and the
+// remaining arguments.
+func splitCommand(arguments []string) (string, []string, error) {
+	if len(arguments) == 0 || strings.HasPrefix(arguments[0], "-") {
+		return commands[0].name, arguments, nil
+	}
+	for _, command := range commands {
+		if arguments[0] == command.name {
+			return command.name, arguments[1:], nil
+		}
 	}
+	return "", nil, fmt.Errorf("Unknown command %q, expected search, count or open", arguments[0])
+}
 
-	hits := &Hits{}
-	nextPage := 1
-	for nextPage != 0 && nextPage < 101 {
-		time.Sleep(1 * time.Second)
-		pageHits, _, err := fetchGrepApp(nextPage, args)
+func parseArguments(arguments []string) (*Arguments, error) {
+	command, arguments, err := splitCommand(arguments)
+	if err != nil {
+		return nil, err
+	}
+	args := &Arguments{Sort: []string{"repo", "path"}}
+	fs := flag.NewFlagSet("grepgithub "+command, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s -q QUERY [flags]\n\n", fs.Name())
+		for _, c := range commands {
+			if c.name == command {
+				fmt.Fprintf(fs.Output(), "%s.\n", c.description)
+			}
+		}
+		fmt.Fprintln(fs.Output(), "\nCommands, search when omitted:")
+		for _, c := range commands {
+			fmt.Fprintf(fs.Output(), "  %-8s %s\n", c.name, c.description)
+		}
+		fmt.Fprintln(fs.Output(), "\nFlags:")
+		fs.PrintDefaults()
+		fmt.Fprintln(fs.Output(), "\nEnvironment variables, overridden by flags:")
+		for _, envFlag := range envFlags {
+			fmt.Fprintf(fs.Output(), "  %-25s default for -%s\n", envFlag.env, envFlag.flag)
+		}
+		fmt.Fprintf(fs.Output(), "\nExit status is %d if matches were found, %d if none were found, %d on error, %d if some pages failed and %d if -timeout passed.\n", exitMatches, exitNoMatches, exitError, exitPartial, exitTimeout)
+	}
+	fs.StringVar(&args.Query, "q", "", "Query string, required. Use - to read it from stdin")
+	fs.Func("any", "Comma separated terms to search one after the other, printing files matching any of them. Replaces -q", func(value string) error {
+		args.Any = nil
+		for _, term := range strings.Split(value, ",") {
+			if term = strings.TrimSpace(term); term != "" && !slices.Contains(args.Any, term) {
+				args.Any = append(args.Any, term)
+			}
+		}
+		if len(args.Any) == 0 {
+			return errors.New("expected comma separated terms")
+		}
+		return nil
+	})
+	fs.BoolVar(&args.CaseSensitive, "c", false, "Case sensitive search")
+	fs.BoolVar(&args.UseRegex, "r", false, "Use regex query. grep.app uses RE2 like syntax, so most Go patterns work. Cannot be used with -w")
+	fs.BoolVar(&args.WholeWords, "w", false, "Search whole words. Cannot be used with -r")
+	fs.StringVar(&args.RepoFilter, "frepo", "", "Filter repository")
+	fs.StringVar(&args.PathFilter, "fpath", "", "Filter path")
+	fs.IntVar(&args.ContextAfter, "A", 0, "Also print up to this many unmatched snippet lines after each match")
+	fs.IntVar(&args.ContextBefore, "B", 0, "Also print up to this many unmatched snippet lines before each match")
+	fs.BoolVar(&args.RawSnippet, "raw-snippet", false, "Add each file's whole snippet as plain text to JSON output")
+	fs.StringVar(&args.LangFilter, "flang", "", "Filter language (eg. Python,C,Java). Use comma for multiple values")
+	fs.Func("xrepo", "Exclude repositories matching this regex", func(value string) (err error) {
+		args.RepoExclude, err = regexp.Compile(value)
+		return err
+	})
+	fs.Func("xpath", "Exclude paths matching this regex", func(value string) (err error) {
+		args.PathExclude, err = regexp.Compile(value)
+		return err
+	})
+	fs.Func("ext", "Comma separated file extensions to keep, eg. .proto,.tmpl. Unlike -flang, any extension works", func(value string) (err error) {
+		args.Extensions, err = parseExtensions(value)
+		return err
+	})
+	fs.StringVar(&args.FilterText, "filter-text", "", "Only keep matched lines containing this text, ignoring case unless -c, and the files left with any")
+	fs.Func("exclude-text", "Drop matched lines matching this regex, such as comments or fixtures, and the files left without any", func(value string) (err error) {
+		args.TextExclude, err = regexp.Compile(value)
+		return err
+	})
+	fs.StringVar(&args.Format, "format", "text", "Output format: text, json, jsonl, events, ndjson-per-line, csv, markdown, sarif, junit or sql. Events are JSON Lines wrapping each file in a typed, timestamped envelope, ndjson-per-line a JSON record per matched line. SARIF 2.1.0 logs can be uploaded to GitHub code scanning, JUnit XML reports each matched file as a failed test, SQL is a script for sqlite3 as with -sqlite")
+	fs.StringVar(&args.SQLite, "sqlite", "", "Also store the results in this SQLite database, creating repos, files and matches tables and recording the query and time of each run. Repeated runs update rows rather than duplicating them")
+	fs.BoolVar(&args.JsonOutput, "json", false, "JSON output, same as -format json")
+	fs.BoolVar(&args.JsonlOutput, "jsonl", false, "JSON Lines output with one file per line, same as -format jsonl. Sorted like other output, so only printed as pages arrive with -sort none or -stream")
+	fs.BoolVar(&args.CsvOutput, "csv", false, "CSV output with repo,path,line_number,line columns, same as -format csv")
+	fs.BoolVar(&args.Count, "count", false, "Only print the total number of matching files, not lines, reported by grep.app, or of repositories with -repos-only. Cannot be used with output formats")
+	fs.BoolVar(&args.CountLines, "count-lines", false, "Scan every page and only print the total number of matched lines, rather than the files -count reports. Cannot be used with output formats")
+	fs.BoolVar(&args.DryRun, "dry-run", false, "Print the URL of every page that could be requested, up to -max-pages, without requesting any")
+	fs.BoolVar(&args.Stats, "stats", false, "Print the number of matched files, lines and repositories and the elapsed time to stderr")
+	fs.BoolVar(&args.ReposOnly, "repos-only", false, "Print the sorted list of matching repositories. With -count, print how many there are")
+	fs.BoolVar(&args.PathsOnly, "paths-only", false, "Print the sorted list of matching files as repo/path. With -count, print how many there are")
+	fs.BoolVar(&args.CloneURLs, "clone-urls", false, "Print the sorted clone URL of each matching GitHub repository, for piping into xargs -n1 git clone. With -count, print how many there are")
+	fs.BoolVar(&args.StripRepo, "strip-repo", false, "Leave the repository out of -paths-only, listing each distinct path once")
+	fs.BoolVar(&args.ByRepo, "by-repo", false, "Print each repository once with its number of matched files and lines, busiest first")
+	fs.BoolVar(&args.ByLang, "by-lang", false, "Print each language once with its number of matched files and lines, busiest first. Languages are guessed from file names")
+	fs.IntVar(&args.Top, "top", 0, "Only print the N busiest repositories or languages with -by-repo or -by-lang. 0 means all")
+	fs.BoolVar(&args.Pretty, "pretty", false, "Indent JSON output. Ignored for other formats")
+	fs.BoolVar(&args.ColorJSON, "color-json", false, "Color the keys, strings, numbers and literals of -json -pretty output for reading. Like other colors, only on terminals unless -color always, and never with -m")
+	fs.BoolVar(&args.WithStars, "with-stars", false, "Look up the GitHub stars of each matched repository, once per repository, and add them to the output. Repositories are left without stars once GitHub rate limits the lookups")
+	fs.StringVar(&args.GithubToken, "github-token", "", "GitHub token for -with-stars lookups, raising GitHub's rate limit")
+	fs.IntVar(&args.GithubRate, "github-rate", 0, fmt.Sprintf("Requests an hour sent to each GitHub host by -with-stars and -download, in bursts of up to as many. Beyond the rate, or once GitHub reports its limit almost reached, -download waits while -with-stars skips lookups, leaving stars unknown. 0 uses GitHub's limit of %d, or %d with -github-token", githubRate, githubTokenRate))
+	fs.Func("fields", "Comma separated fields to output: repo, path, lang, url, stars, line_number and text. Selecting stars implies -with-stars. Selecting line_number or text writes one record per line", func(value string) (err error) {
+		args.Fields, err = parseFields(value)
+		return err
+	})
+	fs.Func("sort", "Comma separated keys to order files by: repo, path, lines, the most matched lines first, or stars, the most starred repositories first with -with-stars (default repo,path). Use none to keep grep.app's order and print text, -jsonl, events and ndjson-per-line output as pages arrive", func(value string) (err error) {
+		args.Sort, err = parseSort(value)
+		return err
+	})
+	fs.Func("template", "Go text/template executed for each matched file, eg. '{{.Repo}}:{{.Path}}'. Files have Repo, Path, Lang, URL, Stars and Lines with LineNumber, Text and URL", func(value string) (err error) {
+		args.Template, err = template.New("template").Parse(value)
+		return err
+	})
+	fs.Func("diff", "Only print the files and lines that differ from this previous -json output, prefixing lines added since with + and those gone with -. Works with text, -json and -jsonl output", func(value string) (err error) {
+		args.Diff, err = loadSnapshot(value)
+		return err
+	})
+	fs.DurationVar(&args.Watch, "watch", 0, "Run the search again at this interval until interrupted, printing only the files and lines not found before. The first run prints everything unless -diff is given")
+	fs.BoolVar(&args.ExitOnNew, "exit-on-new", false, "Stop -watch once a run finds new files or lines")
+	fs.StringVar(&args.Webhook, "webhook", "", "POST the files and lines each -watch run newly finds as JSON to this URL, such as a Slack or Discord incoming webhook")
+	fs.StringVar(&args.WebhookSecret, "webhook-secret", "", "Sign -webhook requests with an HMAC-SHA256 of the body keyed with this secret, sent as X-Grepgithub-Signature: sha256=<hex>")
+	fs.BoolVar(&args.TUI, "tui", false, "Browse matched files interactively once the scan is complete. Arrow keys move, / filters and Enter copies the GitHub URL")
+	fs.Func("copy", "Copy the first matched file's GitHub URL or its first matched line to the clipboard: url or line. Requires -limit 1", func(value string) error {
+		if value != "url" && value != "line" {
+			return errors.New("expected url or line")
+		}
+		args.Copy = value
+		return nil
+	})
+	fs.StringVar(&args.Download, "download", "", "Save the raw content of each matched file in a GitHub repository under this directory as repo/path, waiting -delay between files and skipping files already there")
+	fs.BoolVar(&args.Open, "open", false, fmt.Sprintf("Open the GitHub page of each matched file in the browser. Only opens the first file unless -limit is given, up to %d", maxOpen))
+	fs.BoolVar(&args.Links, "links", false, "Add GitHub permalinks to matched lines of repositories that look like owner/name")
+	fs.StringVar(&args.OutputFile, "o", "", "Output file path")
+	fs.BoolVar(&args.Monochrome, "m", false, "Monochrome output, same as -color never")
+	fs.StringVar(&args.Color, "color", "auto", "When to color output: auto, always or never. Auto colors only terminal output")
+	matchColor := fs.String("color-match", "green", "Color of highlighted matches: black, red, green, yellow, blue, magenta, cyan, white or an ANSI code such as 1;31")
+	fs.DurationVar(&args.PageDelay, "delay", 1*time.Second, "Delay between page requests (eg. 500ms, 2s). Use 0 to disable")
+	fs.DurationVar(&args.MaxPageDelay, "max-delay", 0, "Raise -delay after each rate limited request, up to this delay, and lower it again once requests go through. 0 keeps -delay fixed")
+	fs.BoolVar(&args.NoSleep, "no-sleep", false, "Send page requests and downloads without any delay, for mirrors and tests. May trip grep.app's rate limiting")
+	fs.DurationVar(&args.Timeout, "timeout", 0, "Stop the whole scan after this long and print the results gathered so far. 0 means no timeout")
+	fs.StringVar(&args.Checkpoint, "checkpoint", "", "Save the scan to this file after every page and resume from it when run again with the same search. The file is removed once the scan completes")
+	fs.IntVar(&args.Head, "head", 0, fmt.Sprintf("Fetch only the first N pages, up to %d, without -delay between them for a quick preview", maxHead))
+	fs.IntVar(&args.MaxPages, "max-pages", grepapp.MaxPages, "Maximum number of result pages to fetch, capped at grep.app's limit of 100")
+	fs.IntVar(&args.PerPage, "per-page", 0, "Files to ask for per page, capped at 100. grep.app does not document a page size, so when it is ignored paging follows the size of the first page. 0 uses the server's default")
+	fs.IntVar(&args.Limit, "limit", 0, "Stop after this many matched files, not lines. 0 means no limit")
+	fs.BoolVar(&args.Stream, "stream", false, "Print text, -jsonl, events or ndjson-per-line output as pages arrive without keeping them in memory, in grep.app's order. Only the most recent files and lines are remembered to drop duplicates")
+	fs.IntVar(&args.MinMatches, "min-matches", 0, "Only print repositories with at least this many matched files")
+	fs.IntVar(&args.MaxFilesPerRepo, "max-files-per-repo", 0, "Only print the first files of each repository, in the order grep.app returned them, up to this many. 0 means no limit")
+	fs.IntVar(&args.MaxLinesPerFile, "max-lines-per-file", 0, "Keep at most this many matched lines per file, marking files that had more. 0 means no limit")
+	fs.BoolVar(&args.FirstLineOnly, "first-line-only", false, "Keep only the first matched line of each file")
+	fs.IntVar(&args.Concurrency, "concurrency", 1, "Number of pages to fetch at once. Each worker waits -delay between its requests")
+	fs.IntVar(&args.Retries, "retries", 3, "Number of retries for failed or rate limited requests")
+	fs.DurationVar(&args.RetryBase, "retry-base", 500*time.Millisecond, "Base delay for exponential retry backoff")
+	fs.BoolVar(&args.Strict, "strict", false, "Stop with an error when any page fails instead of printing the remaining pages, and reject unknown -flang languages")
+	fs.StringVar(&args.BaseURL, "base-url", grepapp.DefaultBaseURL, "Base URL of grep.app or a compatible mirror")
+	proxy := fs.String("proxy", "", "Proxy URL for requests, overriding HTTP_PROXY, HTTPS_PROXY and NO_PROXY")
+	fs.BoolVar(&args.Cache, "cache", false, "Cache responses on disk and reuse them for repeated searches without waiting -delay")
+	fs.DurationVar(&args.CacheTTL, "cache-ttl", 24*time.Hour, "How long cached responses stay fresh. Use 0 to keep them forever")
+	fs.BoolVar(&args.NoCache, "no-cache", false, "Ignore cached responses but cache the fresh ones, refreshing the cache")
+	fs.Var(&args.Verbose, "v", "Log each page request and repositories that are not owner/name slugs to stderr. Repeat to also log retries")
+	fs.Var(&args.Verbose, "verbose", "Same as -v")
+	fs.BoolVar(&args.Quiet, "quiet", false, "Only write results and errors, without progress, -stats or warnings")
+	configPath := fs.String("config", defaultConfigPath(), "Config file with default values for any of these flags, keyed by flag name")
+	if err := fs.Parse(arguments); err != nil {
+		return nil, err
+	}
+	// Defaults are overridden by the config file, then the environment, then flags
+	explicit := explicitFlags(fs)
+	if err := applyConfig(fs, *configPath, explicit); err != nil {
+		return nil, err
+	}
+	if err := applyEnv(fs, explicit); err != nil {
+		return nil, err
+	}
+	switch command {
+	case "count":
+		args.Count = true
+	case "open":
+		args.Open = true
+	}
+
+	if args.Query != "" && args.Any != nil {
+		return nil, errors.New("-q and -any cannot be used together")
+	}
+	if args.Query == "" && args.Any == nil {
+		return nil, errors.New("Query string is required")
+	}
+	if args.Any != nil && args.Count && !args.listing() {
+		return nil, errors.New("-count only works with -any together with -repos-only or -paths-only")
+	}
+	if args.Query == "-" {
+		query, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("Cannot read query from stdin: %w", err)
+		}
+		args.Query = strings.TrimRight(string(query), "\r\n")
+		if args.Query == "" {
+			return nil, errors.New("Query read from stdin is empty")
+		}
+	}
+	// grep.app answers an invalid regex with no matches rather than an
+	// error. Its syntax is close enough to Go's RE2 to check it here.
+	if args.UseRegex {
+		for _, query := range args.queries() {
+			if _, err := regexp.Compile(query); err != nil {
+				return nil, fmt.Errorf("Invalid regex %q: %w", query, err)
+			}
+		}
+	}
+	var selected []string
+	if args.JsonOutput {
+		selected = append(selected, "json")
+	}
+	if args.JsonlOutput {
+		selected = append(selected, "jsonl")
+	}
+	if args.CsvOutput {
+		selected = append(selected, "csv")
+	}
+	if len(selected) > 1 {
+		return nil, fmt.Errorf("-%s cannot be used together", strings.Join(selected, " and -"))
+	}
+	if len(selected) == 1 {
+		args.Format = selected[0]
+	}
+	if args.Count || args.CountLines {
+		// A format from the config file or environment is a default for
+		// searches, not a conflict
+		if explicit["format"] {
+			selected = append(selected, "format")
+		}
+		name := "count"
+		if args.CountLines {
+			name = "count-lines"
+		}
+		if len(selected) > 0 {
+			return nil, fmt.Errorf("-%s cannot be used with -%s", name, selected[0])
+		}
+	}
+	if args.ColorJSON && (args.Format != "json" || !args.Pretty) {
+		return nil, errors.New("-color-json only works with -json -pretty")
+	}
+	if args.ByRepo && args.ByLang {
+		return nil, errors.New("-by-repo and -by-lang cannot be used together")
+	}
+	if args.Count && (args.ByRepo || args.ByLang) {
+		return nil, errors.New("-count cannot be used with -by-repo or -by-lang")
+	}
+	if args.ReposOnly && args.PathsOnly || args.CloneURLs && (args.ReposOnly || args.PathsOnly) {
+		return nil, errors.New("-repos-only, -paths-only and -clone-urls cannot be used together")
+	}
+	if args.listing() && (args.ByRepo || args.ByLang || args.Fields != nil || args.Template != nil) {
+		return nil, errors.New("-repos-only, -paths-only and -clone-urls cannot be used with -by-repo, -by-lang, -fields or -template")
+	}
+	if args.StripRepo && !args.PathsOnly {
+		return nil, errors.New("-strip-repo requires -paths-only")
+	}
+	if args.Template != nil && (args.Count || args.ByRepo || args.ByLang || args.Fields != nil) {
+		return nil, errors.New("-template cannot be used with -count, -by-repo, -by-lang or -fields")
+	}
+	if args.Fields != nil && (args.Count || args.ByRepo || args.ByLang) {
+		return nil, errors.New("-fields cannot be used with -count, -by-repo or -by-lang")
+	}
+	if args.Open {
+		if args.Count || args.DryRun {
+			return nil, errors.New("-open cannot be used with -count or -dry-run")
+		}
+		// Guard against opening a tab for every match
+		if !explicit["limit"] {
+			args.Limit = 1
+		}
+		if args.Limit == 0 || args.Limit > maxOpen {
+			return nil, fmt.Errorf("-open opens at most %d files, use a lower -limit", maxOpen)
+		}
+	}
+	if args.Download != "" && (args.Count || args.DryRun) {
+		return nil, errors.New("-download cannot be used with -count or -dry-run")
+	}
+	if args.Diff != nil {
+		if args.Format != "text" && args.Format != "json" && args.Format != "jsonl" {
+			return nil, errors.New("-diff only works with text, -json or -jsonl output")
+		}
+		if args.Stream || args.Count || args.DryRun || args.listing() || args.ByRepo || args.ByLang || args.TUI || args.Fields != nil || args.Template != nil {
+			return nil, errors.New("-diff cannot be used with -stream, -count, -dry-run, -repos-only, -paths-only, -by-repo, -by-lang, -tui, -fields or -template")
+		}
+	}
+	if args.Watch < 0 {
+		return nil, errors.New("Watch interval cannot be negative")
+	}
+	if args.ExitOnNew && args.Watch == 0 {
+		return nil, errors.New("-exit-on-new requires -watch")
+	}
+	if args.Watch > 0 {
+		if !streamingFormats[args.Format] {
+			return nil, errors.New("-watch only works with text, -jsonl, events or ndjson-per-line output")
+		}
+		if args.Stream || args.Count || args.DryRun || args.listing() || args.ByRepo || args.ByLang || args.TUI || args.Checkpoint != "" || args.SQLite != "" || args.Download != "" || args.Copy != "" || args.Open || args.Stats {
+			return nil, errors.New("-watch cannot be used with -stream, -count, -dry-run, -repos-only, -paths-only, -by-repo, -by-lang, -tui, -checkpoint, -sqlite, -download, -copy, -open or -stats")
+		}
+	}
+	if args.Webhook != "" {
+		if args.Watch == 0 {
+			return nil, errors.New("-webhook requires -watch")
+		}
+		if u, err := url.Parse(args.Webhook); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, fmt.Errorf("Invalid webhook URL %q, expected something like https://hooks.example.com/services/T000", args.Webhook)
+		}
+	}
+	if args.WebhookSecret != "" && args.Webhook == "" {
+		return nil, errors.New("-webhook-secret requires -webhook")
+	}
+	if args.CountLines && (args.Count || args.DryRun || args.Stream || args.listing() || args.ByRepo || args.ByLang || args.TUI || args.Diff != nil || args.Watch > 0 || args.Fields != nil || args.Template != nil) {
+		return nil, errors.New("-count-lines cannot be used with -count, -dry-run, -stream, -repos-only, -paths-only, -clone-urls, -by-repo, -by-lang, -tui, -diff, -watch, -fields or -template")
+	}
+	if args.SQLite != "" && (args.Stream || args.DryRun || args.Count && !args.listing()) {
+		return nil, errors.New("-sqlite cannot be used with -stream, -dry-run or -count without -repos-only or -paths-only")
+	}
+	if args.Copy != "" && args.Limit != 1 {
+		return nil, errors.New("-copy requires -limit 1")
+	}
+	if args.TUI && (args.Count || args.OutputFile != "" || args.listing() || args.ByRepo || args.ByLang || args.Fields != nil || args.Template != nil || len(selected) > 0 || explicit["format"]) {
+		return nil, errors.New("-tui cannot be used with -count, -o, -repos-only, -paths-only, -by-repo, -by-lang, -fields, -template or output formats")
+	}
+	if slices.Contains(args.Fields, "url") {
+		args.Links = true
+	}
+	if slices.Contains(args.Fields, "stars") {
+		args.WithStars = true
+	}
+	if slices.Contains(args.Sort, "stars") && !args.WithStars {
+		return nil, errors.New("-sort stars requires -with-stars")
+	}
+	if args.Top < 0 {
+		return nil, errors.New("Top cannot be negative")
+	}
+	if args.Top > 0 && !args.ByRepo && !args.ByLang {
+		return nil, errors.New("-top requires -by-repo or -by-lang")
+	}
+	if _, ok := formats[args.Format]; !ok {
+		return nil, fmt.Errorf("Unknown output format %q", args.Format)
+	}
+	if args.PageDelay < 0 {
+		return nil, errors.New("Delay cannot be negative")
+	}
+	if args.MaxPageDelay != 0 && args.MaxPageDelay < args.PageDelay {
+		return nil, errors.New("Max delay cannot be below -delay")
+	}
+	if args.ContextBefore < 0 || args.ContextAfter < 0 {
+		return nil, errors.New("Context lines cannot be negative")
+	}
+	if args.Timeout < 0 {
+		return nil, errors.New("Timeout cannot be negative")
+	}
+	if args.PerPage < 0 {
+		return nil, errors.New("Per page cannot be negative")
+	}
+	if args.Limit < 0 {
+		return nil, errors.New("Limit cannot be negative")
+	}
+	if args.FirstLineOnly && (args.ContextBefore > 0 || args.ContextAfter > 0) {
+		return nil, errors.New("-first-line-only cannot be used with -A or -B")
+	}
+	if args.Stream {
+		if !streamingFormats[args.Format] || explicit["sort"] || args.MinMatches > 0 || args.ByRepo || args.ByLang || args.listing() || args.TUI || args.Stats || args.Download != "" || args.Copy != "" || args.Open {
+			return nil, errors.New("-stream only works with text, -jsonl, events or ndjson-per-line output, without -sort, -min-matches, -by-repo, -by-lang, -repos-only, -paths-only, -tui, -stats, -download, -copy or -open")
+		}
+		args.Sort = nil
+	}
+	if args.Checkpoint != "" && (args.Stream || args.DryRun || args.Count && !args.listing()) {
+		return nil, errors.New("-checkpoint cannot be used with -stream, -dry-run or -count without -repos-only or -paths-only")
+	}
+	if args.MinMatches < 0 {
+		return nil, errors.New("Min matches cannot be negative")
+	}
+	if args.MinMatches > 0 && args.Count && !args.listing() {
+		return nil, errors.New("-count cannot be used with -min-matches unless with -repos-only or -paths-only")
+	}
+	if args.MaxFilesPerRepo < 0 {
+		return nil, errors.New("Max files per repo cannot be negative")
+	}
+	if args.MaxFilesPerRepo > 0 && (args.Stream || args.Count && !args.listing()) {
+		return nil, errors.New("-max-files-per-repo cannot be used with -stream, or -count without -repos-only or -paths-only")
+	}
+	if args.MaxLinesPerFile < 0 {
+		return nil, errors.New("Max lines per file cannot be negative")
+	}
+	if args.Concurrency < 1 {
+		return nil, errors.New("Concurrency must be at least 1")
+	}
+	if args.GithubRate < 0 {
+		return nil, errors.New("GitHub rate cannot be negative")
+	}
+	if args.Retries < 0 {
+		return nil, errors.New("Retries cannot be negative")
+	}
+	if args.RetryBase < 0 {
+		return nil, errors.New("Retry base delay cannot be negative")
+	}
+	if u, err := url.Parse(args.BaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("Invalid base URL %q, expected something like %s", args.BaseURL, grepapp.DefaultBaseURL)
+	}
+	if args.Quiet && args.Verbose > 0 {
+		return nil, errors.New("-quiet and -verbose cannot be used together")
+	}
+	if args.NoSleep {
+		if explicit["delay"] || explicit["max-delay"] {
+			return nil, errors.New("-no-sleep cannot be used with -delay or -max-delay")
+		}
+		args.PageDelay, args.MaxPageDelay = 0, 0
+		if !args.Quiet {
+			fmt.Fprintln(fs.Output(), "Warning: -no-sleep sends requests back to back, which may trip grep.app's rate limiting")
+		}
+	}
+	if args.Head < 0 {
+		return nil, errors.New("Head cannot be negative")
+	}
+	if args.Head > 0 {
+		if explicit["max-pages"] {
+			return nil, errors.New("-head cannot be used with -max-pages")
+		}
+		if args.Head > maxHead {
+			return nil, fmt.Errorf("-head fetches at most %d pages without delay, use -max-pages for more", maxHead)
+		}
+		// Too few pages to hammer grep.app, so they are fetched back to back
+		args.MaxPages, args.PageDelay, args.MaxPageDelay = args.Head, 0, 0
+	}
+	// Misspelled languages silently match nothing
+	if msgs := unknownLanguages(args.LangFilter); len(msgs) > 0 {
+		if args.Strict {
+			return nil, errors.New(strings.Join(msgs, "\n"))
+		}
+		for _, msg := range msgs {
+			if !args.Quiet {
+				fmt.Fprintf(fs.Output(), "Warning: %s\n", msg)
+			}
+		}
+	}
+	if *proxy != "" {
+		u, err := url.Parse(*proxy)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5") {
+			return nil, fmt.Errorf("Invalid proxy URL %q, expected something like http://proxy.example.com:8080", *proxy)
+		}
+		args.Proxy = u
+	}
+	if args.CacheTTL < 0 {
+		return nil, errors.New("Cache TTL cannot be negative")
+	}
+	if args.Cache || args.NoCache {
+		dir, err := os.UserCacheDir()
 		if err != nil {
-			fail(err.Error())
+			return nil, fmt.Errorf("Cannot locate cache directory: %w", err)
 		}
-		hits.Merge(pageHits)
-		nextPage++
+		args.CacheDir = filepath.Join(dir, "grepgithub")
 	}
 
-	jsonOut, err := json.Marshal(hits)
+	switch args.Color {
+	case "auto", "always", "never":
+	default:
+		return nil, fmt.Errorf("Unknown color mode %q, expected auto, always or never", args.Color)
+	}
+	if args.MatchColor, err = parseMatchColor(*matchColor); err != nil {
+		return nil, err
+	}
+
+	return args, nil
+}
+
+func main() {
+	args, err := parseArguments(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(exitMatches)
+	}
 	if err != nil {
 		fail(err.Error())
 	}
-	fmt.Println(string(jsonOut))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	dest := os.Stdout
+	if args.OutputFile != "" {
+		f, err := os.Create(args.OutputFile)
+		if err != nil {
+			fail(fmt.Sprintf("Cannot open output file: %s", err))
+		}
+		dest = f
+	}
+
+	args.Monochrome = !useColor(args, dest)
+
+	err = run(ctx, args, dest)
+	if args.OutputFile != "" {
+		if closeErr := dest.Close(); closeErr != nil && (err == nil || errors.Is(err, errNoMatches)) {
+			err = closeErr
+		}
+	}
+	stop()
+	var partial *partialError
+	switch {
+	case errors.Is(err, errNoMatches):
+		if args.Format == "text" && !args.Quiet {
+			fmt.Fprintln(os.Stderr, "No matches found")
+		}
+		os.Exit(exitNoMatches)
+	case errors.As(err, &partial):
+		log.Printf("Error: %s", errorMessage(err))
+		os.Exit(exitPartial)
+	case errors.Is(err, errTimeout):
+		log.Printf("Error: %s", err)
+		os.Exit(exitTimeout)
+	case err != nil:
+		fail(errorMessage(err))
+	}
+	os.Exit(exitMatches)
 }
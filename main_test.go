@@ -1,155 +1,453 @@
 package main_test
 
 import (
-	"encoding/json"
+	"bytes"
+	"context"
 	"fmt"
-	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	grepgithub "github.com/aviadhahami/grepgithub-go"
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
 	"github.com/stretchr/testify/assert"
 )
 
-type Hit struct {
-	Repo    string `json:"repo"`
-	Path    string `json:"path"`
-	Snippet string `json:"snippet"`
+func TestMain(m *testing.M) {
+	// Keep the user's own config file out of the tests
+	home, err := os.MkdirTemp("", "grepgithub-test")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("HOME", home)
+	os.Setenv("XDG_CONFIG_HOME", home)
+	code := m.Run()
+	os.RemoveAll(home)
+	os.Exit(code)
+}
+
+func TestParseArgumentsDefaults(t *testing.T) {
+	args, err := grepgithub.ParseArguments([]string{"-q", "foo"})
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", args.Query)
+	assert.Equal(t, "text", args.Format)
+	assert.Equal(t, time.Second, args.PageDelay)
+	assert.Equal(t, 100, args.MaxPages)
+	assert.Equal(t, 1, args.Concurrency)
+	assert.Equal(t, "https://grep.app", args.BaseURL)
+	assert.Empty(t, args.CacheDir)
+}
+
+func TestParseArgumentsVerbosity(t *testing.T) {
+	tests := map[string][]string{
+		"0": {"-q", "foo"},
+		"1": {"-q", "foo", "-v"},
+		"2": {"-q", "foo", "-v", "-verbose"},
+		"3": {"-q", "foo", "-v=3"},
+	}
+	for level, arguments := range tests {
+		args, err := grepgithub.ParseArguments(arguments)
+		assert.NoError(t, err)
+		assert.Equal(t, level, args.Verbose.String(), arguments)
+	}
+}
+
+func TestParseArgumentsCache(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", "/tmp/cache")
+
+	args, err := grepgithub.ParseArguments([]string{"-q", "foo", "-cache"})
+	assert.NoError(t, err)
+	assert.Equal(t, "/tmp/cache/grepgithub", args.CacheDir)
+	assert.Equal(t, 24*time.Hour, args.CacheTTL)
+
+	args, err = grepgithub.ParseArguments([]string{"-q", "foo", "-no-cache"})
+	assert.NoError(t, err)
+	assert.Equal(t, "/tmp/cache/grepgithub", args.CacheDir)
+}
+
+func TestParseArgumentsCommands(t *testing.T) {
+	for _, arguments := range [][]string{{"-q", "foo"}, {"search", "-q", "foo"}} {
+		args, err := grepgithub.ParseArguments(arguments)
+		assert.NoError(t, err)
+		assert.Equal(t, "foo", args.Query)
+		assert.False(t, args.Count)
+	}
+
+	args, err := grepgithub.ParseArguments([]string{"count", "-q", "foo", "-repos-only"})
+	assert.NoError(t, err)
+	assert.True(t, args.Count)
+	assert.True(t, args.ReposOnly)
+
+	args, err = grepgithub.ParseArguments([]string{"open", "-q", "foo"})
+	assert.NoError(t, err)
+	assert.True(t, args.Open)
+	assert.Equal(t, 1, args.Limit)
+
+	args, err = grepgithub.ParseArguments([]string{"-q", "foo", "-open", "-limit", "3"})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, args.Limit)
+}
+
+func TestParseArgumentsFormatShorthands(t *testing.T) {
+	args, err := grepgithub.ParseArguments([]string{"-q", "foo", "-csv"})
+	assert.NoError(t, err)
+	assert.Equal(t, "csv", args.Format)
+
+	args, err = grepgithub.ParseArguments([]string{"-q", "foo", "-format", "jsonl"})
+	assert.NoError(t, err)
+	assert.Equal(t, "jsonl", args.Format)
 }
 
-type Hits struct {
-	Hits []Hit `json:"hits"`
+func TestParseArgumentsValidation(t *testing.T) {
+	tests := []struct {
+		args []string
+		err  string
+	}{
+		{[]string{}, "Query string is required"},
+		{[]string{"find", "-q", "foo"}, `Unknown command "find", expected search, count or open`},
+		{[]string{"count", "-q", "foo", "-json"}, "-count cannot be used with -json"},
+		{[]string{"-q", "foo", "-json", "-csv"}, "-json and -csv cannot be used together"},
+		{[]string{"-q", "foo", "-json", "-jsonl", "-csv"}, "-json and -jsonl and -csv cannot be used together"},
+		{[]string{"-q", "foo", "-count", "-json"}, "-count cannot be used with -json"},
+		{[]string{"-q", "foo", "-count", "-format", "text"}, "-count cannot be used with -format"},
+		{[]string{"-q", "foo", "-by-repo", "-by-lang"}, "-by-repo and -by-lang cannot be used together"},
+		{[]string{"-q", "foo", "-count", "-by-lang"}, "-count cannot be used with -by-repo or -by-lang"},
+		{[]string{"-q", "foo", "-by-repo", "-top", "-1"}, "Top cannot be negative"},
+		{[]string{"-q", "foo", "-top", "3"}, "-top requires -by-repo or -by-lang"},
+		{[]string{"-q", "foo", "-by-repo", "-fields", "repo"}, "-fields cannot be used with -count, -by-repo or -by-lang"},
+		{[]string{"-q", "foo", "-fields", "repo,size"}, `invalid value "repo,size" for flag -fields: Unknown field "size", expected some of repo,path,lang,url,stars,line_number,text`},
+		{[]string{"-q", "foo", "-fields", "repo,repo"}, `invalid value "repo,repo" for flag -fields: Field "repo" is repeated`},
+		{[]string{"-q", "foo", "-template", "{{.Repo"}, `invalid value "{{.Repo" for flag -template: template: template:1: unclosed action`},
+		{[]string{"-q", "foo", "-template", "{{.Repo}}", "-fields", "repo"}, "-template cannot be used with -count, -by-repo, -by-lang or -fields"},
+		{[]string{"-q", "foo", "-flang", "Go,Golang", "-strict"}, `Unknown language "Golang", did you mean "Go"?`},
+		{[]string{"-q", "foo", "-sort", "repo,size"}, `invalid value "repo,size" for flag -sort: Unknown sort key "size", expected repo, path, lines, stars or none`},
+		{[]string{"-q", "foo", "-sort", "stars"}, "-sort stars requires -with-stars"},
+		{[]string{"-q", "foo", "-exit-on-new"}, "-exit-on-new requires -watch"},
+		{[]string{"-q", "foo", "-webhook", "https://hooks.example.com"}, "-webhook requires -watch"},
+		{[]string{"-q", "foo", "-watch", "1m", "-webhook", "hooks.example.com"}, `Invalid webhook URL "hooks.example.com", expected something like https://hooks.example.com/services/T000`},
+		{[]string{"-q", "foo", "-webhook-secret", "secret"}, "-webhook-secret requires -webhook"},
+		{[]string{"-q", "foo", "-watch", "1m", "-json"}, "-watch only works with text, -jsonl, events or ndjson-per-line output"},
+		{[]string{"-q", "foo", "-watch", "1m", "-paths-only"}, "-watch cannot be used with -stream, -count, -dry-run, -repos-only, -paths-only, -by-repo, -by-lang, -tui, -checkpoint, -sqlite, -download, -copy, -open or -stats"},
+		{[]string{"-q", "foo", "-diff", "missing.json"}, `invalid value "missing.json" for flag -diff: open missing.json: no such file or directory`},
+		{[]string{"-q", "foo", "-quiet", "-v"}, "-quiet and -verbose cannot be used together"},
+		{[]string{"count", "-q", "foo", "-download", "out"}, "-download cannot be used with -count or -dry-run"},
+		{[]string{"-q", "foo", "-open", "-limit", "20"}, "-open opens at most 10 files, use a lower -limit"},
+		{[]string{"open", "-q", "foo", "-limit", "0"}, "-open opens at most 10 files, use a lower -limit"},
+		{[]string{"open", "-q", "foo", "-dry-run"}, "-open cannot be used with -count or -dry-run"},
+		{[]string{"-q", "foo", "-any", "bar,baz"}, "-q and -any cannot be used together"},
+		{[]string{"-any", " , "}, `invalid value " , " for flag -any: expected comma separated terms`},
+		{[]string{"-any", "bar,baz", "-count"}, "-count only works with -any together with -repos-only or -paths-only"},
+		{[]string{"-q", "foo", "-min-matches", "-1"}, "Min matches cannot be negative"},
+		{[]string{"count", "-q", "foo", "-min-matches", "2"}, "-count cannot be used with -min-matches unless with -repos-only or -paths-only"},
+		{[]string{"-q", "foo", "-ext", " ,."}, `invalid value " ,." for flag -ext: expected comma separated extensions such as .proto,.tmpl`},
+		{[]string{"-q", "foo", "-first-line-only", "-A", "2"}, "-first-line-only cannot be used with -A or -B"},
+		{[]string{"-q", "foo", "-stream", "-json"}, "-stream only works with text, -jsonl, events or ndjson-per-line output, without -sort, -min-matches, -by-repo, -by-lang, -repos-only, -paths-only, -tui, -stats, -download, -copy or -open"},
+		{[]string{"-q", "foo", "-stream", "-sort", "lines"}, "-stream only works with text, -jsonl, events or ndjson-per-line output, without -sort, -min-matches, -by-repo, -by-lang, -repos-only, -paths-only, -tui, -stats, -download, -copy or -open"},
+		{[]string{"-q", "foo", "-copy", "url"}, "-copy requires -limit 1"},
+		{[]string{"-q", "foo", "-limit", "1", "-copy", "path"}, `invalid value "path" for flag -copy: expected url or line`},
+		{[]string{"-q", "foo", "-tui", "-by-repo"}, "-tui cannot be used with -count, -o, -repos-only, -paths-only, -by-repo, -by-lang, -fields, -template or output formats"},
+		{[]string{"-q", "foo", "-tui", "-json"}, "-tui cannot be used with -count, -o, -repos-only, -paths-only, -by-repo, -by-lang, -fields, -template or output formats"},
+		{[]string{"-q", "foo", "-repos-only", "-paths-only"}, "-repos-only, -paths-only and -clone-urls cannot be used together"},
+		{[]string{"-q", "foo", "-clone-urls", "-repos-only"}, "-repos-only, -paths-only and -clone-urls cannot be used together"},
+		{[]string{"-q", "foo", "-paths-only", "-by-repo"}, "-repos-only, -paths-only and -clone-urls cannot be used with -by-repo, -by-lang, -fields or -template"},
+		{[]string{"-q", "foo", "-strip-repo"}, "-strip-repo requires -paths-only"},
+		{[]string{"-q", "foo", "-format", "xml"}, `Unknown output format "xml"`},
+		{[]string{"-q", "foo", "-delay", "-1s"}, "Delay cannot be negative"},
+		{[]string{"-q", "foo", "-delay", "2s", "-max-delay", "1s"}, "Max delay cannot be below -delay"},
+		{[]string{"-q", "foo", "-per-page", "-1"}, "Per page cannot be negative"},
+		{[]string{"-q", "foo", "-no-sleep", "-delay", "2s"}, "-no-sleep cannot be used with -delay or -max-delay"},
+		{[]string{"-q", "foo", "-head", "-1"}, "Head cannot be negative"},
+		{[]string{"-q", "foo", "-github-rate", "-1"}, "GitHub rate cannot be negative"},
+		{[]string{"-q", "foo", "-json", "-color-json"}, "-color-json only works with -json -pretty"},
+		{[]string{"-q", "foo", "-max-files-per-repo", "-1"}, "Max files per repo cannot be negative"},
+		{[]string{"-q", "foo", "-max-files-per-repo", "2", "-count"}, "-max-files-per-repo cannot be used with -stream, or -count without -repos-only or -paths-only"},
+		{[]string{"-q", "foo", "-count-lines", "-json"}, "-count-lines cannot be used with -json"},
+		{[]string{"-q", "foo", "-count-lines", "-by-repo"}, "-count-lines cannot be used with -count, -dry-run, -stream, -repos-only, -paths-only, -clone-urls, -by-repo, -by-lang, -tui, -diff, -watch, -fields or -template"},
+		{[]string{"-q", "foo", "-head", "6"}, "-head fetches at most 5 pages without delay, use -max-pages for more"},
+		{[]string{"-q", "foo", "-head", "2", "-max-pages", "3"}, "-head cannot be used with -max-pages"},
+		{[]string{"-q", "foo", "-stream", "-checkpoint", "scan.json"}, "-checkpoint cannot be used with -stream, -dry-run or -count without -repos-only or -paths-only"},
+		{[]string{"-q", "foo", "-A", "-1"}, "Context lines cannot be negative"},
+		{[]string{"-q", "foo", "-timeout", "-1s"}, "Timeout cannot be negative"},
+		{[]string{"-q", "foo", "-limit", "-1"}, "Limit cannot be negative"},
+		{[]string{"-q", "foo", "-max-lines-per-file", "-1"}, "Max lines per file cannot be negative"},
+		{[]string{"-q", "foo", "-concurrency", "0"}, "Concurrency must be at least 1"},
+		{[]string{"-q", "foo", "-retries", "-1"}, "Retries cannot be negative"},
+		{[]string{"-q", "foo", "-retry-base", "-1s"}, "Retry base delay cannot be negative"},
+		{[]string{"-q", "foo", "-cache", "-cache-ttl", "-1h"}, "Cache TTL cannot be negative"},
+		{[]string{"-q", "foo", "-base-url", "grep.app"}, `Invalid base URL "grep.app", expected something like https://grep.app`},
+		{[]string{"-q", "foo", "-proxy", "proxy.example.com:8080"}, `Invalid proxy URL "proxy.example.com:8080", expected something like http://proxy.example.com:8080`},
+		{[]string{"-q", "foo", "-color", "sometimes"}, `Unknown color mode "sometimes", expected auto, always or never`},
+		{[]string{"-q", "foo", "-color-match", "purple"}, `Unknown match color "purple", expected a color name such as red, yellow or cyan, or an ANSI code such as 1;31`},
+		{[]string{"-q", "foo(", "-r"}, "Invalid regex \"foo(\": error parsing regexp: missing closing ): `foo(`"},
+		{[]string{"-any", "foo,[a-", "-r"}, "Invalid regex \"[a-\": error parsing regexp: missing closing ]: `[a-`"},
+		{[]string{"-q", "foo", "-xrepo", "("}, "invalid value \"(\" for flag -xrepo: error parsing regexp: missing closing ): `(`"},
+	}
+	for _, test := range tests {
+		_, err := grepgithub.ParseArguments(test.args)
+		assert.EqualError(t, err, test.err, test.args)
+	}
+}
+
+func TestParseArgumentsCanBeCalledTwice(t *testing.T) {
+	_, err := grepgithub.ParseArguments([]string{"-q", "foo"})
+	assert.NoError(t, err)
+	_, err = grepgithub.ParseArguments([]string{"-q", "bar"})
+	assert.NoError(t, err)
 }
 
-type Arguments struct {
-	Query         string
-	UseRegex      bool
-	WholeWords    bool
-	CaseSensitive bool
-	RepoFilter    string
-	PathFilter    string
-	LangFilter    string
+func TestPrintURLs(t *testing.T) {
+	args, err := grepgithub.ParseArguments([]string{"-q", "a b&c", "-flang", "Go", "-max-pages", "2", "-dry-run"})
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, grepgithub.PrintURLs(grepgithub.NewClient(args), &buf, args))
+	assert.Equal(t, "https://grep.app/api/search?f.lang=Go&page=1&q=a+b%26c\n"+
+		"https://grep.app/api/search?f.lang=Go&page=2&q=a+b%26c\n", buf.String())
 }
 
-func TestFetchGrepApp(t *testing.T) {
-	// Create a mock HTTP server
+func TestRun(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Check the URL and query parameters
-		assert.Equal(t, "/api/search", r.URL.Path)
-		assert.Equal(t, "application/json", r.Header.Get("Accept"))
-
-		// Check the query parameters
-		expectedQuery := "test"
-		expectedPage := "1"
-		expectedRegex := "true"
-		expectedWords := "false"
-		expectedCase := "true"
-		expectedRepoFilter := "example/repo"
-		expectedPathFilter := "example/path"
-		expectedLangFilter := "go"
-
-		assert.Equal(t, expectedQuery, r.URL.Query().Get("q"))
-		assert.Equal(t, expectedPage, r.URL.Query().Get("page"))
-		assert.Equal(t, expectedRegex, r.URL.Query().Get("regexp"))
-		assert.Equal(t, expectedWords, r.URL.Query().Get("words"))
-		assert.Equal(t, expectedCase, r.URL.Query().Get("case"))
-		assert.Equal(t, expectedRepoFilter, r.URL.Query().Get("f.repo.pattern"))
-		assert.Equal(t, expectedPathFilter, r.URL.Query().Get("f.path.pattern"))
-		assert.Equal(t, expectedLangFilter, r.URL.Query().Get("f.lang"))
-
-		// Return a mock response
-		response := `{
-			"facets": {
-				"count": 10
-			},
-			"hits": {
-				"hits": [
-					{
-						"repo": {
-							"raw": "example/repo"
-						},
-						"path": {
-							"raw": "example/path"
-						},
-						"content": {
-							"snippet": "example snippet"
-						}
-					}
-				]
-			}
-		}`
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte(response))
+		if r.URL.Query().Get("q") == "flaky" && r.URL.Query().Get("page") == "2" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if r.URL.Query().Get("q") == "none" {
+			_, _ = w.Write([]byte(`{"facets":{"count":0},"hits":{"hits":[]}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"facets":{"count":3},"hits":{"hits":[{"repo":{"raw":"example/repo"},"path":{"raw":"main` + r.URL.Query().Get("page") + `.go"},` +
+			`"content":{"snippet":"<table><tr><td><div class=\"lineno\">7</div></td><td><pre><mark>foo</mark>()</pre></td></tr></table>"}}]}}`))
 	}))
 	defer server.Close()
 
-	// Set up the test arguments
-	args := &Arguments{
-		Query:         "test",
-		UseRegex:      true,
-		WholeWords:    false,
-		CaseSensitive: true,
-		RepoFilter:    "example/repo",
-		PathFilter:    "example/path",
-		LangFilter:    "go",
-	}
+	args, err := grepgithub.ParseArguments([]string{"-q", "foo", "-delay", "0", "-m", "-fields", "path", "-base-url", server.URL})
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	assert.NoError(t, grepgithub.Run(context.Background(), args, &buf))
+	assert.Equal(t, "main1.go\nmain2.go\nmain3.go\n", buf.String())
 
-	// Call the function under test
-	hits, count, err := fetchGrepApp(1, args)
-
-	// Check the returned values
-	assert.NoError(t, err)
-	assert.NotNil(t, hits)
-	assert.Equal(t, 1, count)
-	assert.Equal(t, 1, len(hits.Hits))
-
-	// Check the hit data
-	hit := hits.Hits[0]
-	assert.Equal(t, "example/repo", hit.Repo)
-	assert.Equal(t, "example/path", hit.Path)
-	assert.Equal(t, "example snippet", hit.Snippet)
-}
-
-func fetchGrepApp(i int, args *Arguments) (*Hits, string, error) {
-	// Create the request URL
-	url := fmt.Sprintf("%s/api/search?q=%s&page=%d&regexp=%t&words=%t&case=%t&f.repo.pattern=%s&f.path.pattern=%s&f.lang=%s",
-		"https://grep.app",
-		args.Query,
-		i,
-		args.UseRegex,
-		args.WholeWords,
-		args.CaseSensitive,
-		args.RepoFilter,
-		args.PathFilter,
-		args.LangFilter,
-	)
-
-	// Send the HTTP request
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, "", err
-	}
-	defer resp.Body.Close()
+	// Streamed page by page
+	args, err = grepgithub.ParseArguments([]string{"-q", "foo", "-delay", "0", "-m", "-fields", "path", "-sort", "none", "-base-url", server.URL})
+	assert.NoError(t, err)
+	buf.Reset()
+	assert.NoError(t, grepgithub.Run(context.Background(), args, &buf))
+	assert.Equal(t, "main1.go\nmain2.go\nmain3.go\n", buf.String())
 
-	// Check the response status code
-	if resp.StatusCode != http.StatusOK {
-		return nil, "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
+	// Streamed without keeping the hits
+	args, err = grepgithub.ParseArguments([]string{"-q", "foo", "-delay", "0", "-m", "-fields", "path", "-stream", "-limit", "2", "-base-url", server.URL})
+	assert.NoError(t, err)
+	buf.Reset()
+	assert.NoError(t, grepgithub.Run(context.Background(), args, &buf))
+	assert.Equal(t, "main1.go\nmain2.go\n", buf.String())
 
-	// Read the response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, "", err
+	args, err = grepgithub.ParseArguments([]string{"-q", "foo", "-delay", "0", "-repos-only", "-count", "-base-url", server.URL})
+	assert.NoError(t, err)
+	buf.Reset()
+	assert.NoError(t, grepgithub.Run(context.Background(), args, &buf))
+	assert.Equal(t, "1\n", buf.String())
+
+	args, err = grepgithub.ParseArguments([]string{"-q", "flaky", "-delay", "0", "-m", "-fields", "path", "-base-url", server.URL})
+	assert.NoError(t, err)
+	buf.Reset()
+	err = grepgithub.Run(context.Background(), args, &buf)
+	assert.EqualError(t, err, "1 pages failed, results are incomplete: page 2: HTTP 500 "+server.URL+"/api/search?page=2&q=flaky")
+	assert.Equal(t, "main1.go\nmain3.go\n", buf.String())
+
+	args, err = grepgithub.ParseArguments([]string{"-q", "flaky", "-delay", "0", "-m", "-strict", "-base-url", server.URL})
+	assert.NoError(t, err)
+	buf.Reset()
+	assert.EqualError(t, grepgithub.Run(context.Background(), args, &buf), "HTTP 500 "+server.URL+"/api/search?page=2&q=flaky")
+
+	args, err = grepgithub.ParseArguments([]string{"-q", "none", "-delay", "0", "-m", "-base-url", server.URL})
+	assert.NoError(t, err)
+	buf.Reset()
+	assert.ErrorIs(t, grepgithub.Run(context.Background(), args, &buf), grepgithub.ErrNoMatches)
+	assert.Empty(t, buf.String())
+
+	args, err = grepgithub.ParseArguments([]string{"-q", "none", "-delay", "0", "-json", "-base-url", server.URL})
+	assert.NoError(t, err)
+	buf.Reset()
+	assert.ErrorIs(t, grepgithub.Run(context.Background(), args, &buf), grepgithub.ErrNoMatches)
+	assert.Regexp(t, `^\{"schema_version":1,"query":\{"query":"none",.*"timestamp":"[0-9T:-]+Z"\},"total_count":0,"fetched_count":0,"hits":\[\]\}\n$`, buf.String())
+
+	args, err = grepgithub.ParseArguments([]string{"-q", "foo", "-delay", "0", "-retries", "0", "-base-url", "http://127.0.0.1:1"})
+	assert.NoError(t, err)
+	assert.Error(t, grepgithub.Run(context.Background(), args, &buf))
+}
+
+func TestRunAny(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query().Get("q")
+		_, _ = w.Write([]byte(`{"facets":{"count":2},"hits":{"hits":[` +
+			`{"repo":{"raw":"example/repo"},"path":{"raw":"` + q + `.go"}},` +
+			`{"repo":{"raw":"example/repo"},"path":{"raw":"both.go"}}]}}`))
+	}))
+	defer server.Close()
+
+	args, err := grepgithub.ParseArguments([]string{"-any", "foo, bar,foo", "-delay", "0", "-jsonl", "-base-url", server.URL})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"foo", "bar"}, args.Any)
+	var buf bytes.Buffer
+	assert.NoError(t, grepgithub.Run(context.Background(), args, &buf))
+	assert.Equal(t, `{"repo":"example/repo","path":"bar.go","lang":"Go","lines":[],"terms":["bar"]}`+"\n"+
+		`{"repo":"example/repo","path":"both.go","lang":"Go","lines":[],"terms":["foo","bar"]}`+"\n"+
+		`{"repo":"example/repo","path":"foo.go","lang":"Go","lines":[],"terms":["foo"]}`+"\n", buf.String())
+
+	args, err = grepgithub.ParseArguments([]string{"-any", "foo,bar", "-dry-run", "-max-pages", "1", "-base-url", server.URL})
+	assert.NoError(t, err)
+	buf.Reset()
+	assert.NoError(t, grepgithub.Run(context.Background(), args, &buf))
+	assert.Equal(t, server.URL+"/api/search?page=1&q=foo\n"+server.URL+"/api/search?page=1&q=bar\n", buf.String())
+}
+
+// lockedBuffer is a bytes.Buffer that a test server can read while Run
+// writes to it.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestRunJSONLStreamsOnlyWithSortNone(t *testing.T) {
+	var out lockedBuffer
+	var beforePage2 string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := "z.go"
+		if r.URL.Query().Get("page") == "2" {
+			beforePage2 = out.String()
+			path = "a.go"
+		}
+		_, _ = w.Write([]byte(`{"facets":{"count":2},"hits":{"hits":[{"repo":{"raw":"example/repo"},"path":{"raw":"` + path + `"},` +
+			`"content":{"snippet":"<table><tr><td><div class=\"lineno\">1</div></td><td><pre><mark>foo</mark></pre></td></tr></table>"}}]}}`))
+	}))
+	defer server.Close()
+
+	for _, format := range []string{"jsonl", "events", "ndjson-per-line"} {
+		// Sorted by default, so nothing is written before the scan is complete
+		args, err := grepgithub.ParseArguments([]string{"-q", "foo", "-delay", "0", "-format", format, "-base-url", server.URL})
+		assert.NoError(t, err)
+		out.buf.Reset()
+		beforePage2 = ""
+		assert.NoError(t, grepgithub.Run(context.Background(), args, &out))
+		assert.Empty(t, beforePage2, format)
+		assert.Less(t, strings.Index(out.String(), "a.go"), strings.Index(out.String(), "z.go"), format)
+
+		args, err = grepgithub.ParseArguments([]string{"-q", "foo", "-delay", "0", "-format", format, "-sort", "none", "-base-url", server.URL})
+		assert.NoError(t, err)
+		out.buf.Reset()
+		assert.NoError(t, grepgithub.Run(context.Background(), args, &out))
+		assert.Contains(t, beforePage2, "z.go", format)
+		assert.NotContains(t, beforePage2, "a.go", format)
+		assert.Greater(t, strings.Index(out.String(), "a.go"), strings.Index(out.String(), "z.go"), format)
 	}
+}
 
-	// Parse the JSON response
-	var result Hits
-	err = json.Unmarshal(body, &result)
-	if err != nil {
-		return nil, "", err
+func TestRunRateLimited(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") != "1" {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(`{"facets":{"count":2},"hits":{"hits":[{"repo":{"raw":"example/repo"},"path":{"raw":"main.go"}}]}}`))
+	}))
+	defer server.Close()
+
+	args, err := grepgithub.ParseArguments([]string{"-q", "foo", "-delay", "0", "-retries", "0", "-paths-only", "-base-url", server.URL})
+	assert.NoError(t, err)
+	err = grepgithub.Run(context.Background(), args, &bytes.Buffer{})
+	assert.ErrorIs(t, err, grepapp.ErrRateLimited)
+	assert.Contains(t, grepgithub.ErrorMessage(err), "Try a longer -delay")
+	assert.Equal(t, "no matches", grepgithub.ErrorMessage(grepgithub.ErrNoMatches))
+}
+
+func TestRunCountLines(t *testing.T) {
+	row := func(number int, code string) string {
+		return fmt.Sprintf(`<tr><td><div class=\"lineno\">%d</div></td><td><pre>%s</pre></td></tr>`, number, code)
+	}
+	hit := func(path string, rows ...string) string {
+		return `{"repo":{"raw":"example/repo"},"path":{"raw":"` + path + `"},"content":{"snippet":"<table>` + strings.Join(rows, "") + `</table>"}}`
 	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits := []string{
+			hit("main.go", row(1, "<mark>foo</mark>()"), row(2, "bar()"), row(3, "<mark>foo</mark>()")),
+			hit("lib.go", row(7, "<mark>foo</mark>()")),
+		}
+		if r.URL.Query().Get("page") == "2" {
+			hits = []string{hit("cmd.go", row(4, "<mark>foo</mark>()"), row(5, "<mark>foo</mark>()"))}
+		}
+		_, _ = w.Write([]byte(`{"facets":{"count":4},"hits":{"hits":[` + strings.Join(hits, ",") + `]}}`))
+	}))
+	defer server.Close()
+
+	args, err := grepgithub.ParseArguments([]string{"-q", "foo", "-count-lines", "-A", "1", "-delay", "0", "-base-url", server.URL})
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	assert.NoError(t, grepgithub.Run(context.Background(), args, &buf))
+	assert.Equal(t, "5\n", buf.String())
+}
+
+func TestRunHead(t *testing.T) {
+	var pages []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		pages = append(pages, page)
+		_, _ = w.Write([]byte(`{"facets":{"count":1000},"hits":{"hits":[{"repo":{"raw":"example/repo"},"path":{"raw":"file` + page + `.go"}}]}}`))
+	}))
+	defer server.Close()
 
-	// Return the hits and count
-	return &result, result.Hits[0].Snippet, nil
+	args, err := grepgithub.ParseArguments([]string{"-q", "foo", "-head", "3", "-paths-only", "-base-url", server.URL})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), args.PageDelay)
+	var buf bytes.Buffer
+	started := time.Now()
+	assert.NoError(t, grepgithub.Run(context.Background(), args, &buf))
+	assert.Less(t, time.Since(started), time.Second, "pages are fetched without the default delay")
+	assert.Equal(t, []string{"1", "2", "3"}, pages)
+	assert.Equal(t, "example/repo/file1.go\nexample/repo/file2.go\nexample/repo/file3.go\n", buf.String())
+}
+
+func TestRunThroughProxy(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+		_, _ = w.Write([]byte(`{"facets":{"count":0},"hits":{"hits":[]}}`))
+	}))
+	defer proxy.Close()
+
+	args, err := grepgithub.ParseArguments([]string{"-q", "foo", "-delay", "0", "-base-url", "http://grep.example.com", "-proxy", proxy.URL})
+	assert.NoError(t, err)
+	assert.ErrorIs(t, grepgithub.Run(context.Background(), args, &bytes.Buffer{}), grepgithub.ErrNoMatches)
+	assert.Equal(t, []string{"http://grep.example.com/api/search?page=1&q=foo"}, proxied)
+}
+
+func TestRunTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") != "1" {
+			<-r.Context().Done()
+			return
+		}
+		_, _ = w.Write([]byte(`{"facets":{"count":10},"hits":{"hits":[{"repo":{"raw":"example/repo"},"path":{"raw":"main.go"},"content":{"snippet":"<mark>foo</mark>"}}]}}`))
+	}))
+	defer server.Close()
+
+	args, err := grepgithub.ParseArguments([]string{"-q", "foo", "-delay", "0", "-fields", "path", "-timeout", "200ms", "-base-url", server.URL})
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	assert.ErrorIs(t, grepgithub.Run(context.Background(), args, &buf), grepgithub.ErrTimeout)
+	assert.Equal(t, "main.go\n", buf.String())
 }
@@ -0,0 +1,416 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+	"golang.org/x/term"
+)
+
+const C_PATH = "\033[35m"
+
+var ansiRe = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// matchColors maps the names accepted by -color-match to their SGR codes.
+var matchColors = map[string]string{
+	"black":   "30",
+	"red":     "31",
+	"green":   "32",
+	"yellow":  "33",
+	"blue":    "34",
+	"magenta": "35",
+	"cyan":    "36",
+	"white":   "37",
+}
+
+var sgrRe = regexp.MustCompile(`^[0-9]+(;[0-9]+)*$`)
+
+// parseMatchColor returns the escape sequence for a color name or a raw SGR
+// code such as 1;31.
+func parseMatchColor(value string) (string, error) {
+	code, ok := matchColors[value]
+	if !ok {
+		if !sgrRe.MatchString(value) {
+			return "", fmt.Errorf("Unknown match color %q, expected a color name such as red, yellow or cyan, or an ANSI code such as 1;31", value)
+		}
+		code = value
+	}
+	return "\033[" + code + "m", nil
+}
+
+type writeFunc func(w io.Writer, hits *grepapp.Results, args *Arguments) error
+
+var formats = map[string]writeFunc{
+	"text":            writeText,
+	"json":            writeJSON,
+	"jsonl":           writeJSONL,
+	"events":          writeEvents,
+	"ndjson-per-line": writeLineRecords,
+	"csv":             writeCSV,
+	"markdown":        writeMarkdown,
+	"sarif":           writeSARIF,
+	"junit":           writeJUnit,
+	"sql":             writeSQL,
+}
+
+// streamingFormats are written page by page as results arrive rather than
+// once the scan is complete, unless the output is sorted. A file matched on
+// several pages is written once per page. JSON, CSV and Markdown are written
+// whole at the end, as they form a single document.
+var streamingFormats = map[string]bool{
+	"text":            true,
+	"jsonl":           true,
+	"events":          true,
+	"ndjson-per-line": true,
+}
+
+func stripANSI(s string) string {
+	return ansiRe.ReplaceAllString(s, "")
+}
+
+// useColor reports whether output written to w should be colored. In auto
+// mode colors are only used when w is a terminal.
+func useColor(args *Arguments, w io.Writer) bool {
+	if args.Monochrome {
+		return false
+	}
+	switch args.Color {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+func render(w io.Writer, hits *grepapp.Results, args *Arguments) error {
+	decorate(hits, args)
+	if args.Template != nil {
+		return writeTemplate(w, hits, args.Template)
+	}
+	if args.Fields != nil {
+		return writeFields(w, hits, args)
+	}
+	return formats[args.Format](w, hits, args)
+}
+
+// decorate colors or strips the highlighting of hits and adds links, as
+// selected by args.
+func decorate(hits *grepapp.Results, args *Arguments) {
+	if args.Monochrome {
+		monochrome(hits)
+	} else if args.MatchColor != "" && args.MatchColor != grepapp.C_MARK {
+		recolor(hits, args.MatchColor)
+	}
+	if args.Links {
+		addLinks(hits)
+	}
+}
+
+// monochrome strips the highlighting that grep.app snippets are decorated with.
+func monochrome(hits *grepapp.Results) {
+	for i := range hits.Hits {
+		for j := range hits.Hits[i].Lines {
+			hits.Hits[i].Lines[j].Text = stripANSI(hits.Hits[i].Lines[j].Text)
+		}
+	}
+}
+
+// recolor highlights matches with color instead of grepapp.C_MARK.
+func recolor(hits *grepapp.Results, color string) {
+	for i := range hits.Hits {
+		for j := range hits.Hits[i].Lines {
+			hits.Hits[i].Lines[j].Text = strings.ReplaceAll(hits.Hits[i].Lines[j].Text, grepapp.C_MARK, color)
+		}
+	}
+}
+
+// addLinks sets the GitHub permalink of every file and line in a GitHub
+// repository.
+func addLinks(hits *grepapp.Results) {
+	for i := range hits.Hits {
+		hit := &hits.Hits[i]
+		hit.URL = grepapp.GithubURL(hit.Repo, hit.Path, 0)
+		for j := range hit.Lines {
+			hit.Lines[j].URL = grepapp.GithubURL(hit.Repo, hit.Path, hit.Lines[j].LineNumber)
+		}
+	}
+}
+
+// separator follows the location of a line, telling matches from context
+// like grep does.
+func separator(line grepapp.Line) string {
+	if line.Context {
+		return "-"
+	}
+	return ":"
+}
+
+// writeText writes hits grep style. Lines are located by their permalink
+// instead of their number when one is set, and files with dropped lines end
+// with "...".
+func writeText(w io.Writer, hits *grepapp.Results, args *Arguments) error {
+	for i, hit := range hits.Hits {
+		if !args.Monochrome {
+			if i > 0 {
+				if _, err := fmt.Fprintln(w); err != nil {
+					return err
+				}
+			}
+			stars := ""
+			if hit.Stars != nil {
+				stars = fmt.Sprintf(" (%d stars)", *hit.Stars)
+			}
+			if _, err := fmt.Fprintf(w, "%s%s/%s%s%s\n", C_PATH, hit.Repo, hit.Path, grepapp.C_RST, stars); err != nil {
+				return err
+			}
+			for _, line := range hit.Lines {
+				location := strconv.Itoa(line.LineNumber)
+				if line.URL != "" {
+					location = line.URL
+				}
+				if _, err := fmt.Fprintf(w, "%s%s %s\n", location, separator(line), line.Text); err != nil {
+					return err
+				}
+			}
+			if hit.Truncated {
+				if _, err := fmt.Fprintln(w, "..."); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if len(hit.Lines) == 0 {
+			location := hit.Repo + ":" + hit.Path
+			if hit.URL != "" {
+				location = hit.URL
+			}
+			if _, err := fmt.Fprintln(w, location); err != nil {
+				return err
+			}
+		}
+		for _, line := range hit.Lines {
+			location := fmt.Sprintf("%s:%s%s%d", hit.Repo, hit.Path, separator(line), line.LineNumber)
+			if line.URL != "" {
+				location = line.URL
+			}
+			if _, err := fmt.Fprintf(w, "%s%s %s\n", location, separator(line), stripANSI(line.Text)); err != nil {
+				return err
+			}
+		}
+		if hit.Truncated {
+			if _, err := fmt.Fprintf(w, "%s:%s:...\n", hit.Repo, hit.Path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// schemaVersion is the version of the -json document shape. Bump it whenever
+// a field is renamed, removed or changes meaning.
+const schemaVersion = 1
+
+// jsonQuery describes the search a -json document holds the results of.
+type jsonQuery struct {
+	Query         string   `json:"query,omitempty"`
+	Any           []string `json:"any,omitempty"`
+	CaseSensitive bool     `json:"case_sensitive"`
+	Regex         bool     `json:"regex"`
+	WholeWords    bool     `json:"whole_words"`
+	RepoFilter    string   `json:"repo_filter,omitempty"`
+	PathFilter    string   `json:"path_filter,omitempty"`
+	LangFilter    string   `json:"lang_filter,omitempty"`
+	Timestamp     string   `json:"timestamp,omitempty"`
+}
+
+// queryOf returns the search described by args, without a timestamp.
+func queryOf(args *Arguments) jsonQuery {
+	return jsonQuery{
+		Query:         args.Query,
+		Any:           args.Any,
+		CaseSensitive: args.CaseSensitive,
+		Regex:         args.UseRegex,
+		WholeWords:    args.WholeWords,
+		RepoFilter:    args.RepoFilter,
+		PathFilter:    args.PathFilter,
+		LangFilter:    args.LangFilter,
+	}
+}
+
+// jsonDocument is the whole -json output.
+type jsonDocument struct {
+	SchemaVersion int       `json:"schema_version"`
+	Query         jsonQuery `json:"query"`
+	// TotalCount is the number of files grep.app reported, FetchedCount the
+	// number of hits below, fewer when pages were left out or filtered.
+	TotalCount   int              `json:"total_count"`
+	FetchedCount int              `json:"fetched_count"`
+	Hits         []grepapp.Result `json:"hits"`
+}
+
+func writeJSON(w io.Writer, hits *grepapp.Results, args *Arguments) error {
+	doc := jsonDocument{
+		SchemaVersion: schemaVersion,
+		Query:         queryOf(args),
+		TotalCount:    hits.Count,
+		FetchedCount:  len(hits.Hits),
+		Hits:          hits.Hits,
+	}
+	if !args.Started.IsZero() {
+		doc.Query.Timestamp = args.Started.UTC().Format(time.RFC3339)
+	}
+	var jsonOut []byte
+	var err error
+	if args.Pretty {
+		jsonOut, err = json.MarshalIndent(doc, "", "  ")
+	} else {
+		jsonOut, err = json.Marshal(doc)
+	}
+	if err != nil {
+		return err
+	}
+	if args.ColorJSON && !args.Monochrome {
+		jsonOut = colorJSON(jsonOut)
+	}
+	_, err = fmt.Fprintln(w, string(jsonOut))
+	return err
+}
+
+func writeJSONL(w io.Writer, hits *grepapp.Results, args *Arguments) error {
+	enc := json.NewEncoder(w)
+	for _, hit := range hits.Hits {
+		if err := enc.Encode(hit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// event is a line of -format events. Its type tells what it holds, so that
+// other kinds of records can share the stream, hit being the only kind yet.
+type event struct {
+	Type      string          `json:"type"`
+	Timestamp string          `json:"timestamp"`
+	Hit       *grepapp.Result `json:"hit,omitempty"`
+}
+
+// writeEvents writes a hit event per file, timestamped when written.
+func writeEvents(w io.Writer, hits *grepapp.Results, args *Arguments) error {
+	enc := json.NewEncoder(w)
+	for i := range hits.Hits {
+		e := event{Type: "hit", Timestamp: time.Now().UTC().Format(time.RFC3339Nano), Hit: &hits.Hits[i]}
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lineRecord is a line of -format ndjson-per-line, flattening a matched
+// line with its file and search for columnar stores.
+type lineRecord struct {
+	Repo       string `json:"repo"`
+	Path       string `json:"path"`
+	LineNumber int    `json:"line_number"`
+	Text       string `json:"text"`
+	URL        string `json:"url,omitempty"`
+	Query      string `json:"query"`
+}
+
+// writeLineRecords writes a record per matched line, leaving out context
+// lines. With -any, the query is the term that matched the file.
+func writeLineRecords(w io.Writer, hits *grepapp.Results, args *Arguments) error {
+	enc := json.NewEncoder(w)
+	for _, hit := range hits.Hits {
+		query := args.Query
+		if len(hit.Terms) > 0 {
+			query = strings.Join(hit.Terms, ",")
+		}
+		for _, line := range hit.Lines {
+			if line.Context {
+				continue
+			}
+			record := lineRecord{Repo: hit.Repo, Path: hit.Path, LineNumber: line.LineNumber, Text: stripANSI(line.Text), URL: line.URL, Query: query}
+			if err := enc.Encode(record); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func writeCSV(w io.Writer, hits *grepapp.Results, args *Arguments) error {
+	cw := csv.NewWriter(w)
+	header := []string{"repo", "path", "line_number", "line"}
+	if args.Links {
+		header = append(header, "url")
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, hit := range hits.Hits {
+		for _, line := range hit.Lines {
+			if line.Context {
+				continue
+			}
+			record := []string{hit.Repo, hit.Path, strconv.Itoa(line.LineNumber), stripANSI(line.Text)}
+			if args.Links {
+				record = append(record, line.URL)
+			}
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "|", `\|`)
+
+// writeMarkdown writes a table with a row per matched line, meant for
+// pasting into issues and pull requests.
+func writeMarkdown(w io.Writer, hits *grepapp.Results, args *Arguments) error {
+	if _, err := fmt.Fprint(w, "| Repo | Path | Line | Match |\n| --- | --- | ---: | --- |\n"); err != nil {
+		return err
+	}
+	for _, hit := range hits.Hits {
+		path := markdownEscaper.Replace(hit.Path)
+		if hit.URL != "" {
+			path = fmt.Sprintf("[%s](%s)", path, hit.URL)
+		}
+		row := func(line, text string) error {
+			_, err := fmt.Fprintf(w, "| %s | %s | %s | %s |\n", markdownEscaper.Replace(hit.Repo), path, line, text)
+			return err
+		}
+		if hit.MatchedLines() == 0 {
+			if err := row("", ""); err != nil {
+				return err
+			}
+		}
+		for _, line := range hit.Lines {
+			if line.Context {
+				continue
+			}
+			number := strconv.Itoa(line.LineNumber)
+			if line.URL != "" {
+				number = fmt.Sprintf("[%s](%s)", number, line.URL)
+			}
+			if err := row(number, markdownEscaper.Replace(stripANSI(line.Text))); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
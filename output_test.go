@@ -0,0 +1,185 @@
+package main_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	grepgithub "github.com/aviadhahami/grepgithub-go"
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteCSV(t *testing.T) {
+	hits := &grepapp.Results{}
+	hits.AddHit("example/repo", "main.go", 3, "foo("+grepapp.C_MARK+"x, y"+grepapp.C_RST+")")
+	hits.AddHit("example/repo", "main.go", 12, `say "hi"`)
+	hits.Merge(&grepapp.Results{Hits: []grepapp.Result{{Repo: "example/repo", Path: "main.go", Lines: []grepapp.Line{{LineNumber: 4, Text: "context", Context: true}}}}})
+
+	var buf bytes.Buffer
+	assert.NoError(t, grepgithub.WriteCSV(&buf, hits, &grepgithub.Arguments{}))
+	assert.Equal(t, "repo,path,line_number,line\n"+
+		"example/repo,main.go,3,\"foo(x, y)\"\n"+
+		"example/repo,main.go,12,\"say \"\"hi\"\"\"\n", buf.String())
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	hits := &grepapp.Results{}
+	hits.AddHit("example/repo", "main.go", 3, "a "+grepapp.C_MARK+"||"+grepapp.C_RST+` b \| c`)
+	hits.AddHit("example/repo", "main.go", 12, "foo")
+	hits.Merge(&grepapp.Results{Hits: []grepapp.Result{{Repo: "example/repo", Path: "README.md"}}})
+	hits.Merge(&grepapp.Results{Hits: []grepapp.Result{{Repo: "example/repo", Path: "main.go", Lines: []grepapp.Line{{LineNumber: 4, Text: "context", Context: true}}}}})
+
+	var buf bytes.Buffer
+	assert.NoError(t, grepgithub.WriteMarkdown(&buf, hits, &grepgithub.Arguments{}))
+	assert.Equal(t, "| Repo | Path | Line | Match |\n"+
+		"| --- | --- | ---: | --- |\n"+
+		"| example/repo | main.go | 3 | "+`a \|\| b \\\| c`+" |\n"+
+		"| example/repo | main.go | 12 | foo |\n"+
+		"| example/repo | README.md |  |  |\n", buf.String())
+}
+
+func TestWriteTextMonochrome(t *testing.T) {
+	hits := &grepapp.Results{}
+	hits.AddHit("example/repo", "main.go", 3, "foo("+grepapp.C_MARK+"x"+grepapp.C_RST+")")
+	hits.Merge(&grepapp.Results{Hits: []grepapp.Result{{Repo: "example/repo", Path: "README.md"}}})
+	hits.Merge(&grepapp.Results{Hits: []grepapp.Result{{Repo: "example/repo", Path: "main.go", Lines: []grepapp.Line{{LineNumber: 4, Text: "bar", Context: true}}}}})
+	hits.Merge(&grepapp.Results{Hits: []grepapp.Result{{Repo: "example/repo", Path: "big.go", Lines: []grepapp.Line{{LineNumber: 1, Text: "x"}}, Truncated: true}}})
+
+	var buf bytes.Buffer
+	assert.NoError(t, grepgithub.WriteText(&buf, hits, &grepgithub.Arguments{Monochrome: true}))
+	assert.Equal(t, "example/repo:main.go:3: foo(x)\n"+
+		"example/repo:main.go-4- bar\n"+
+		"example/repo:README.md\n"+
+		"example/repo:big.go:1: x\n"+
+		"example/repo:big.go:...\n", buf.String())
+}
+
+func TestRenderMatchColor(t *testing.T) {
+	for value, color := range map[string]string{"green": "\x1b[32m", "cyan": "\x1b[36m", "1;31": "\x1b[1;31m"} {
+		args, err := grepgithub.ParseArguments([]string{"-q", "foo", "-color-match", value})
+		assert.NoError(t, err)
+
+		hits := &grepapp.Results{}
+		hits.AddHit("example/repo", "main.go", 3, "foo("+grepapp.C_RST+grepapp.C_MARK+"x"+grepapp.C_RST+")")
+		var buf bytes.Buffer
+		assert.NoError(t, grepgithub.Render(&buf, hits, args))
+		assert.Contains(t, buf.String(), "3: foo("+grepapp.C_RST+color+"x"+grepapp.C_RST+")", value)
+
+		args.Monochrome = true
+		buf.Reset()
+		assert.NoError(t, grepgithub.Render(&buf, hits, args))
+		assert.NotContains(t, buf.String(), "\x1b", value)
+	}
+}
+
+func TestRenderLinks(t *testing.T) {
+	hits := &grepapp.Results{}
+	hits.AddHit("example/repo", "main.go", 3, "foo")
+	hits.AddHit("git.example.com/repo", "main.go", 4, "bar")
+
+	var buf bytes.Buffer
+	assert.NoError(t, grepgithub.Render(&buf, hits, &grepgithub.Arguments{Format: "text", Monochrome: true, Links: true}))
+	assert.Equal(t, "https://github.com/example/repo/blob/HEAD/main.go#L3: foo\n"+
+		"git.example.com/repo:main.go:4: bar\n", buf.String())
+
+	buf.Reset()
+	assert.NoError(t, grepgithub.Render(&buf, hits, &grepgithub.Arguments{Format: "jsonl", Links: true}))
+	assert.Contains(t, buf.String(), `{"line_number":3,"text":"foo","url":"https://github.com/example/repo/blob/HEAD/main.go#L3"}`)
+	assert.Contains(t, buf.String(), `{"line_number":4,"text":"bar"}`)
+}
+
+func TestWriteJSONL(t *testing.T) {
+	hits := &grepapp.Results{}
+	hits.AddHit("example/repo", "main.go", 3, "foo")
+	hits.AddHit("example/repo", "README.md", 7, "bar")
+
+	var buf bytes.Buffer
+	assert.NoError(t, grepgithub.WriteJSONL(&buf, hits, &grepgithub.Arguments{}))
+	assert.Equal(t, `{"repo":"example/repo","path":"main.go","lines":[{"line_number":3,"text":"foo"}]}`+"\n"+
+		`{"repo":"example/repo","path":"README.md","lines":[{"line_number":7,"text":"bar"}]}`+"\n", buf.String())
+}
+
+func TestWriteEvents(t *testing.T) {
+	hits := &grepapp.Results{}
+	hits.AddHit("example/repo", "main.go", 3, "foo")
+	hits.AddHit("example/repo", "README.md", 7, "bar")
+
+	var buf bytes.Buffer
+	assert.NoError(t, grepgithub.WriteEvents(&buf, hits, &grepgithub.Arguments{}))
+	assert.Regexp(t, `^`+
+		`\{"type":"hit","timestamp":"\d{4}-\d\d-\d\dT[\d:.]+Z","hit":\{"repo":"example/repo","path":"main.go","lines":\[\{"line_number":3,"text":"foo"\}\]\}\}\n`+
+		`\{"type":"hit","timestamp":"[^"]+","hit":\{"repo":"example/repo","path":"README.md","lines":\[\{"line_number":7,"text":"bar"\}\]\}\}\n$`, buf.String())
+}
+
+func TestWriteLineRecords(t *testing.T) {
+	hits := &grepapp.Results{}
+	hits.AddHit("example/repo", "main.go", 3, grepapp.C_MARK+"foo"+grepapp.C_RST+"()")
+	hits.AddHit("example/repo", "main.go", 9, "foo")
+	hits.AddHit("example/repo", "README.md", 7, "foo bar")
+	hits.Merge(&grepapp.Results{Hits: []grepapp.Result{{Repo: "example/repo", Path: "main.go", Lines: []grepapp.Line{{LineNumber: 4, Text: "context", Context: true}}}}})
+	hits.Merge(&grepapp.Results{Hits: []grepapp.Result{{Repo: "example/other", Path: "empty.go"}}})
+
+	var buf bytes.Buffer
+	assert.NoError(t, grepgithub.WriteLineRecords(&buf, hits, &grepgithub.Arguments{SearchOptions: grepapp.SearchOptions{Query: "foo"}}))
+	records := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	assert.Len(t, records, 3, "one record per matched line")
+	assert.Equal(t, `{"repo":"example/repo","path":"main.go","line_number":3,"text":"foo()","query":"foo"}`, records[0])
+	assert.Equal(t, `{"repo":"example/repo","path":"README.md","line_number":7,"text":"foo bar","query":"foo"}`, records[2])
+
+	hits.Hits[0].Terms = []string{"bar"}
+	buf.Reset()
+	assert.NoError(t, grepgithub.WriteLineRecords(&buf, hits, &grepgithub.Arguments{Any: []string{"foo", "bar"}}))
+	assert.Contains(t, buf.String(), `"line_number":3,"text":"foo()","query":"bar"}`)
+}
+
+func TestWriteJSONPretty(t *testing.T) {
+	hits := &grepapp.Results{}
+	hits.AddHit("example/repo", "main.go", 3, "foo")
+
+	var compact, pretty bytes.Buffer
+	assert.NoError(t, grepgithub.WriteJSON(&compact, hits, &grepgithub.Arguments{}))
+	assert.NoError(t, grepgithub.WriteJSON(&pretty, hits, &grepgithub.Arguments{Pretty: true}))
+
+	assert.Equal(t, 1, strings.Count(compact.String(), "\n"))
+	assert.NotContains(t, compact.String(), "  ")
+	assert.Contains(t, pretty.String(), "\n  \"hits\": [\n    {\n      \"repo\": \"example/repo\"")
+	assert.JSONEq(t, compact.String(), pretty.String())
+}
+
+func TestRenderNonTerminalIsMonochrome(t *testing.T) {
+	hits := &grepapp.Results{}
+	hits.AddHit("example/repo", "main.go", 3, "foo("+grepapp.C_MARK+"x"+grepapp.C_RST+")")
+
+	for _, format := range []string{"text", "json", "jsonl", "csv", "markdown"} {
+		var buf bytes.Buffer
+		args := &grepgithub.Arguments{Format: format, Color: "auto"}
+		args.Monochrome = !grepgithub.UseColor(args, &buf)
+
+		assert.NoError(t, grepgithub.Render(&buf, hits, args))
+		assert.NotContains(t, buf.String(), "\x1b", format)
+	}
+
+	assert.True(t, grepgithub.UseColor(&grepgithub.Arguments{Color: "always"}, &bytes.Buffer{}))
+	assert.False(t, grepgithub.UseColor(&grepgithub.Arguments{Color: "always", Monochrome: true}, &bytes.Buffer{}))
+}
+
+func TestWriteJSONMetadata(t *testing.T) {
+	hits := &grepapp.Results{Count: 250}
+	hits.AddHit("example/repo", "main.go", 3, "foo")
+	args := &grepgithub.Arguments{Started: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	args.Query = "foo"
+	args.UseRegex = true
+	args.LangFilter = "Go"
+
+	var buf bytes.Buffer
+	assert.NoError(t, grepgithub.WriteJSON(&buf, hits, args))
+	assert.JSONEq(t, `{
+		"schema_version": 1,
+		"query": {"query": "foo", "case_sensitive": false, "regex": true, "whole_words": false, "lang_filter": "Go", "timestamp": "2024-05-01T12:00:00Z"},
+		"total_count": 250,
+		"fetched_count": 1,
+		"hits": [{"repo": "example/repo", "path": "main.go", "lines": [{"line_number": 3, "text": "foo"}]}]
+	}`, buf.String())
+}
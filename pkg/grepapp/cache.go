@@ -0,0 +1,71 @@
+package grepapp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Cache keeps raw search responses on disk, keyed by request URL, so that
+// repeated searches skip both the request and the page delay.
+type Cache struct {
+	Dir string
+	// TTL is how long responses stay fresh. Zero keeps them forever.
+	TTL time.Duration
+	// Refresh ignores cached responses while still storing new ones.
+	Refresh bool
+}
+
+func NewCache(dir string, ttl time.Duration) *Cache {
+	return &Cache{Dir: dir, TTL: ttl}
+}
+
+func (c *Cache) path(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// get returns the fresh response stored for url. A nil cache is empty.
+func (c *Cache) get(url string) ([]byte, bool) {
+	if c == nil || c.Refresh {
+		return nil, false
+	}
+	path := c.path(url)
+	info, err := os.Stat(path)
+	if err != nil || (c.TTL > 0 && time.Since(info.ModTime()) > c.TTL) {
+		return nil, false
+	}
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	return body, true
+}
+
+// put stores body as the response for url. Responses are written to a
+// temporary file first so that concurrent readers never see partial ones.
+func (c *Cache) put(url string, body []byte) error {
+	if c == nil {
+		return nil
+	}
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(c.Dir, "response-*")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), c.path(url))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
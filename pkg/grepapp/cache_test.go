@@ -0,0 +1,69 @@
+package grepapp_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+	"github.com/stretchr/testify/assert"
+)
+
+func countingServer(requests *atomic.Int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		_, _ = w.Write([]byte(`{"facets":{"count":1},"hits":{"hits":[{"repo":{"raw":"example/repo"},"path":{"raw":"main.go"},` +
+			`"content":{"snippet":"<table><tr><td><div class=\"lineno\">7</div></td><td><pre><mark>test</mark></pre></td></tr></table>"}}]}}`))
+	}))
+}
+
+func TestCacheServesRepeatedSearches(t *testing.T) {
+	var requests atomic.Int32
+	server := countingServer(&requests)
+	defer server.Close()
+
+	client := newTestClient(server.URL)
+	client.Cache = grepapp.NewCache(t.TempDir(), time.Hour)
+	opts := &grepapp.SearchOptions{Query: "test", MaxPages: 1}
+
+	first, err := client.Search(context.Background(), opts)
+	assert.NoError(t, err)
+	// The page delay is skipped for cached pages
+	client.PageDelay = time.Hour
+	second, err := client.Search(context.Background(), opts)
+	assert.NoError(t, err)
+
+	assert.Equal(t, int32(1), requests.Load())
+	assert.Equal(t, first, second)
+}
+
+func TestCacheExpiresAndRefreshes(t *testing.T) {
+	var requests atomic.Int32
+	server := countingServer(&requests)
+	defer server.Close()
+
+	client := newTestClient(server.URL)
+	client.Cache = grepapp.NewCache(t.TempDir(), time.Nanosecond)
+	opts := &grepapp.SearchOptions{Query: "test"}
+
+	_, _, err := client.Page(context.Background(), opts, 1)
+	assert.NoError(t, err)
+	time.Sleep(time.Millisecond)
+	_, _, err = client.Page(context.Background(), opts, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), requests.Load())
+
+	client.Cache.TTL = time.Hour
+	client.Cache.Refresh = true
+	_, _, err = client.Page(context.Background(), opts, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), requests.Load())
+
+	client.Cache.Refresh = false
+	_, _, err = client.Page(context.Background(), opts, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), requests.Load())
+}
@@ -0,0 +1,12 @@
+package grepapp
+
+import "time"
+
+var (
+	DecodeResults = decodeResults
+	LanguageOf    = languageOf
+)
+
+func (c *Client) CurrentPageDelay() time.Duration {
+	return c.pageDelay()
+}
@@ -0,0 +1,572 @@
+// Package grepapp searches public git repositories through the grep.app API.
+package grepapp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	C_MARK = "\033[32m"
+	C_RST  = "\033[0m"
+
+	// MaxPages is the last page grep.app is willing to serve.
+	MaxPages = 100
+
+	// MaxPerPage is the largest page size SearchOptions.PerPage asks for.
+	MaxPerPage = 100
+
+	// DefaultBaseURL is the public grep.app endpoint.
+	DefaultBaseURL = "https://grep.app"
+)
+
+var defaultHTTPClient = NewHTTPClient(nil)
+
+// NewHTTPClient returns a client with a 30 second timeout that sends requests
+// through proxy, or through the proxy named by the HTTP_PROXY, HTTPS_PROXY and
+// NO_PROXY environment variables when proxy is nil.
+func NewHTTPClient(proxy *url.URL) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	if proxy != nil {
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	return &http.Client{Timeout: 30 * time.Second, Transport: transport}
+}
+
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// ErrRateLimited is wrapped by the error of a request that grep.app still
+// answered with 429 Too Many Requests once retries were exhausted.
+var ErrRateLimited = errors.New("grep.app rate limited")
+
+// ErrStop can be returned from a WalkFunc to stop paging without an error.
+var ErrStop = errors.New("grepapp: stop paging")
+
+type SearchOptions struct {
+	Query         string
+	CaseSensitive bool
+	UseRegex      bool
+	WholeWords    bool
+	RepoFilter    string
+	PathFilter    string
+	LangFilter    string
+	MaxPages      int
+	// PerPage, when above 0, asks for that many files per page, up to
+	// MaxPerPage. grep.app does not document a page size parameter, so
+	// servers may ignore it; Walk follows the size of the first page anyway.
+	PerPage int
+	// FirstPage, when above 1, makes Walk start from that page, as when
+	// resuming an interrupted walk. Its count then comes from that page.
+	FirstPage int
+	// ContextBefore and ContextAfter are the number of unmatched snippet
+	// lines kept before and after each matched line.
+	ContextBefore int
+	ContextAfter  int
+	// RawSnippet keeps each file's whole snippet as plain text in
+	// Result.Snippet.
+	RawSnippet bool
+}
+
+// Page is a single page of search results.
+type Page struct {
+	Number  int
+	Results *Results
+	// Count is the total number of matching files reported by grep.app.
+	Count int
+	// Err is set when the page could not be fetched and the client is set
+	// to SkipFailedPages. Results is empty in that case.
+	Err error
+}
+
+// WalkFunc is called by Walk for every fetched page.
+type WalkFunc func(page *Page) error
+
+type Client struct {
+	// HTTPClient is used for all requests. When nil, a client with a
+	// 30 second timeout is used.
+	HTTPClient *http.Client
+	// BaseURL points the client at grep.app or a compatible mirror.
+	BaseURL   string
+	UserAgent string
+	PageDelay time.Duration
+	// MaxPageDelay, when above PageDelay, makes the delay adaptive: each
+	// rate limited response raises it by DelayStep up to MaxPageDelay, and
+	// every DelayRecovery pages fetched in a row lower it again.
+	MaxPageDelay time.Duration
+	// Concurrency is the number of pages fetched at once. Values below 2
+	// fetch pages one after another.
+	Concurrency int
+	Retries     int
+	RetryBase   time.Duration
+	// Cache, when set, serves repeated requests from disk.
+	Cache *Cache
+	// SkipFailedPages hands pages after the first that cannot be fetched to
+	// the WalkFunc with Err set instead of ending the walk. The first page is
+	// needed to know how many there are, so its failure always ends it.
+	SkipFailedPages bool
+	// Logger receives page fetches at info level and retry decisions at
+	// debug level. When nil, nothing is logged.
+	Logger *slog.Logger
+
+	pacer pacer
+}
+
+func NewClient() *Client {
+	return &Client{
+		HTTPClient:  defaultHTTPClient,
+		BaseURL:     DefaultBaseURL,
+		UserAgent:   "grepgithub-go",
+		PageDelay:   1 * time.Second,
+		Concurrency: 1,
+		Retries:     3,
+		RetryBase:   500 * time.Millisecond,
+	}
+}
+
+// PageURL returns the API URL requested for a page of results.
+func (c *Client) PageURL(opts *SearchOptions, page int) string {
+	params := url.Values{}
+	params.Set("q", opts.Query)
+	params.Set("page", strconv.Itoa(page))
+	if opts.PerPage > 0 {
+		params.Set("per_page", strconv.Itoa(min(opts.PerPage, MaxPerPage)))
+	}
+
+	if opts.UseRegex {
+		params.Set("regexp", "true")
+	} else if opts.WholeWords {
+		params.Set("words", "true")
+	}
+
+	if opts.CaseSensitive {
+		params.Set("case", "true")
+	}
+	if opts.RepoFilter != "" {
+		params.Set("f.repo.pattern", opts.RepoFilter)
+	}
+	if opts.PathFilter != "" {
+		params.Set("f.path.pattern", opts.PathFilter)
+	}
+	if opts.LangFilter != "" {
+		params.Set("f.lang", opts.LangFilter)
+	}
+
+	baseURL := c.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return strings.TrimRight(baseURL, "/") + "/api/search?" + params.Encode()
+}
+
+// Search fetches every page of results for opts and merges them. Results
+// are never nil: when the search fails partway, such as when ctx is
+// canceled or its deadline passes, the pages fetched so far are returned
+// along with the error, which callers can check for context.Canceled or
+// context.DeadlineExceeded before deciding whether partial results will do.
+func (c *Client) Search(ctx context.Context, opts *SearchOptions) (*Results, error) {
+	results := &Results{Hits: []Result{}}
+	var pageErrs []error
+	err := c.Walk(ctx, opts, func(page *Page) error {
+		if page.Err != nil {
+			pageErrs = append(pageErrs, page.Err)
+		}
+		if page.Number == 1 {
+			results.Count = page.Count
+		}
+		results.Merge(page.Results)
+		return nil
+	})
+	if err == nil {
+		err = errors.Join(pageErrs...)
+	}
+	return results, err
+}
+
+// Walk fetches result pages and calls fn for each of them in page order,
+// waiting PageDelay, or the adaptive delay, before each request. Paging
+// ends when the reported count is exhausted, a page comes back empty or
+// opts.MaxPages is reached. Errors returned by fn stop the walk and are
+// returned as is, except for ErrStop.
+//
+// With Concurrency above 1, pages after the first are fetched by that many
+// workers at once, each observing PageDelay between its own requests.
+func (c *Client) Walk(ctx context.Context, opts *SearchOptions, fn WalkFunc) error {
+	firstPage := max(opts.FirstPage, 1)
+	lastPage := min(max(opts.MaxPages, 1), MaxPages)
+	for number := firstPage; number <= lastPage; number++ {
+		if number > firstPage && c.Concurrency > 1 {
+			return c.walkConcurrent(ctx, opts, number, lastPage, fn)
+		}
+		page, err := c.delayedPage(ctx, opts, number)
+		if err != nil {
+			if page = c.failedPage(ctx, number == firstPage, number, err); page == nil {
+				return err
+			}
+		}
+		// grep.app does not report its page size, so derive it from the first page
+		if perPage := len(page.Results.Hits); number == firstPage && perPage > 0 {
+			lastPage = min(lastPage, (page.Count+perPage-1)/perPage)
+		}
+		if done, err := visit(page, fn); done {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) walkConcurrent(ctx context.Context, opts *SearchOptions, firstPage, lastPage int, fn WalkFunc) error {
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	type fetched struct {
+		page *Page
+		err  error
+	}
+	pending := make(map[int]chan fetched, lastPage-firstPage+1)
+	for number := firstPage; number <= lastPage; number++ {
+		pending[number] = make(chan fetched, 1)
+	}
+
+	numbers := make(chan int)
+	go func() {
+		defer close(numbers)
+		for number := firstPage; number <= lastPage; number++ {
+			select {
+			case numbers <- number:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	for i := 0; i < min(c.Concurrency, lastPage-firstPage+1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for number := range numbers {
+				page, err := c.delayedPage(ctx, opts, number)
+				pending[number] <- fetched{page: page, err: err}
+			}
+		}()
+	}
+
+	// Pages are handed to fn in order, whichever worker finishes first
+	for number := firstPage; number <= lastPage; number++ {
+		var result fetched
+		select {
+		case result = <-pending[number]:
+		case <-ctx.Done():
+			return fmt.Errorf("page %d: %w", number, ctx.Err())
+		}
+		if result.err != nil {
+			if result.page = c.failedPage(ctx, false, number, result.err); result.page == nil {
+				return result.err
+			}
+		}
+		if done, err := visit(result.page, fn); done {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) delayedPage(ctx context.Context, opts *SearchOptions, number int) (*Page, error) {
+	// Cached pages cost grep.app nothing, so there is no need to wait for them
+	if _, cached := c.Cache.get(c.PageURL(opts, number)); !cached {
+		if err := sleepCtx(ctx, c.pageDelay()); err != nil {
+			return nil, err
+		}
+	}
+	results, count, err := c.Page(ctx, opts, number)
+	if err != nil {
+		return nil, err
+	}
+	return &Page{Number: number, Results: results, Count: count}, nil
+}
+
+// failedPage returns the page to hand to the WalkFunc in place of one that
+// failed with err, or nil if the walk should end with err instead. The
+// first page of a walk tells how many there are, so it cannot be skipped.
+func (c *Client) failedPage(ctx context.Context, first bool, number int, err error) *Page {
+	if !c.SkipFailedPages || first || ctx.Err() != nil {
+		return nil
+	}
+	return &Page{Number: number, Results: &Results{}, Err: err}
+}
+
+// visit hands page to fn and reports whether the walk is over. fn may
+// modify the page, so emptiness is checked beforehand.
+func visit(page *Page, fn WalkFunc) (bool, error) {
+	empty := page.Err == nil && len(page.Results.Hits) == 0
+	if err := fn(page); err != nil {
+		if errors.Is(err, ErrStop) {
+			return true, nil
+		}
+		return true, err
+	}
+	return empty, nil
+}
+
+// Page fetches a single page of results along with the total count reported
+// by grep.app. Cancellation errors wrap ctx.Err().
+func (c *Client) Page(ctx context.Context, opts *SearchOptions, page int) (*Results, int, error) {
+	url := c.PageURL(opts, page)
+	if body, ok := c.Cache.get(url); ok {
+		results, count, err := decodeResults(bytes.NewReader(body), opts)
+		if err == nil {
+			c.logger().Info("page cached", "page", page, "url", url, "hits", len(results.Hits), "count", count)
+			c.logOddRepos(page, results)
+		}
+		return results, count, err
+	}
+	start := time.Now()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.UserAgent)
+	// Setting this ourselves disables the transport's transparent
+	// decompression, so bodies are decompressed below
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	resp, err := c.doWithRetry(ctx, req)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, 0, fmt.Errorf("page %d: %w", page, ctxErr)
+		}
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	log := c.logger().With("page", page, "url", url, "status", resp.StatusCode)
+
+	var body io.Reader = resp.Body
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, 0, fmt.Errorf("page %d: %w", page, err)
+		}
+		defer gz.Close()
+		body = gz
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		log.Info("page failed", "latency", time.Since(start))
+		return nil, 0, statusError(resp.StatusCode, url, body)
+	}
+	// The body is only kept in memory when it is to be cached
+	var data []byte
+	if c.Cache != nil {
+		if data, err = io.ReadAll(body); err != nil {
+			return nil, 0, fmt.Errorf("page %d: %w", page, err)
+		}
+		body = bytes.NewReader(data)
+	}
+	results, count, err := decodeResults(body, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	log.Info("page fetched", "hits", len(results.Hits), "count", count, "latency", time.Since(start))
+	c.logOddRepos(page, results)
+	c.pageFetched()
+	if data != nil {
+		// Failing to cache only costs a request next time
+		_ = c.Cache.put(url, data)
+	}
+	return results, count, nil
+}
+
+// logOddRepos logs the repositories of a page that are not owner/name
+// slugs, once each, as links to them cannot be made.
+func (c *Client) logOddRepos(page int, results *Results) {
+	logged := make(map[string]bool)
+	for _, hit := range results.Hits {
+		if _, ok := NormalizeRepo(hit.Repo); !ok && !logged[hit.Repo] {
+			logged[hit.Repo] = true
+			c.logger().Info("unexpected repository slug", "page", page, "repo", hit.Repo)
+		}
+	}
+}
+
+// maxErrorBody is how much of an error response is kept in the error.
+const maxErrorBody = 4 << 10
+
+// statusError describes a response with an unexpected status, including
+// the start of its body, which often tells what grep.app did not like.
+func statusError(status int, url string, body io.Reader) error {
+	data, _ := io.ReadAll(io.LimitReader(body, maxErrorBody))
+	err := fmt.Errorf("HTTP %d %s", status, url)
+	if text := strings.TrimSpace(string(data)); text != "" {
+		err = fmt.Errorf("%w: %s", err, text)
+	}
+	if status == http.StatusTooManyRequests {
+		err = fmt.Errorf("%w: %w", ErrRateLimited, err)
+	}
+	return err
+}
+
+// decodeResults parses a grep.app search response into results and the
+// total count it reports, keeping the snippet context lines asked for by
+// opts.
+func decodeResults(r io.Reader, opts *SearchOptions) (*Results, int, error) {
+	var data struct {
+		Facets struct {
+			Count int `json:"count"`
+		} `json:"facets"`
+		Hits struct {
+			Hits []struct {
+				Repo struct {
+					Raw string `json:"raw"`
+				} `json:"repo"`
+				Path struct {
+					Raw string `json:"raw"`
+				} `json:"path"`
+				Content struct {
+					Snippet string `json:"snippet"`
+				} `json:"content"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+
+	err := json.NewDecoder(r).Decode(&data)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	results := &Results{ContextAfter: opts.ContextAfter}
+	for _, hitData := range data.Hits.Hits {
+		repo, _ := NormalizeRepo(hitData.Repo.Raw)
+		path := hitData.Path.Raw
+		snippet := hitData.Content.Snippet
+		hit := results.hit(repo, path)
+		hit.Lang = languageOf(path)
+		if opts.RawSnippet {
+			hit.Snippet = plainSnippet(snippet)
+		}
+		lines := snippetLines(snippet)
+		for i, snippetLine := range lines {
+			if !snippetLine.matched() {
+				continue
+			}
+			line := Line{LineNumber: snippetLine.number, Text: highlight(snippetLine.html), Matches: matchSpans(snippetLine.html)}
+			results.hit(repo, path).addLine(line, results.MaxLines, results.ContextAfter)
+			for j := max(i-opts.ContextBefore, 0); j <= min(i+opts.ContextAfter, len(lines)-1); j++ {
+				if !lines[j].matched() {
+					results.hit(repo, path).addLine(Line{LineNumber: lines[j].number, Text: highlight(lines[j].html), Context: true}, 0, 0)
+				}
+			}
+		}
+	}
+
+	count := data.Facets.Count
+	return results, count, nil
+}
+
+func (c *Client) doWithRetry(ctx context.Context, req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := c.httpClient().Do(req)
+		if err == nil && resp.StatusCode == http.StatusTooManyRequests {
+			c.rateLimited()
+		}
+		if ctx.Err() != nil || attempt >= c.Retries {
+			return resp, err
+		}
+		if err == nil && !retryableStatus(resp.StatusCode) {
+			return resp, nil
+		}
+
+		wait := backoff(c.RetryBase, attempt)
+		log := c.logger().With("url", req.URL.String(), "attempt", attempt+1)
+		if err == nil {
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+				wait = retryAfter
+				log = log.With("retry_after", true)
+			}
+			log = log.With("status", resp.StatusCode)
+			resp.Body.Close()
+		} else {
+			log = log.With("error", err)
+		}
+		log.Debug("retrying request", "wait", wait)
+		if err := sleepCtx(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (c *Client) logger() *slog.Logger {
+	if c.Logger != nil {
+		return c.Logger
+	}
+	return discardLogger
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return defaultHTTPClient
+}
+
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func backoff(base time.Duration, attempt int) time.Duration {
+	d := base << attempt
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,561 @@
+package grepapp_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestClient(serverURL string) *grepapp.Client {
+	client := grepapp.NewClient()
+	client.PageDelay = 0
+	client.RetryBase = time.Millisecond
+	client.BaseURL = serverURL
+	return client
+}
+
+// rewriteTransport sends every request to target, whatever host it was built for.
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (t rewriteTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.URL.Scheme = t.target.Scheme
+	r.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestFetchGrepApp(t *testing.T) {
+	// Create a mock HTTP server
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Check the URL and query parameters
+		assert.Equal(t, "/api/search", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Accept"))
+		assert.Equal(t, "grepgithub-go", r.Header.Get("User-Agent"))
+
+		// Decoded by the server, so the encoding must have survived the trip
+		assert.Equal(t, "foo bar&baz #1", r.URL.Query().Get("q"))
+		assert.Equal(t, "1", r.URL.Query().Get("page"))
+		assert.Equal(t, "true", r.URL.Query().Get("regexp"))
+		assert.Empty(t, r.URL.Query().Get("words"))
+		assert.Equal(t, "true", r.URL.Query().Get("case"))
+		assert.Equal(t, "example/repo", r.URL.Query().Get("f.repo.pattern"))
+		assert.Equal(t, "src/ünï+code", r.URL.Query().Get("f.path.pattern"))
+		assert.Equal(t, "go", r.URL.Query().Get("f.lang"))
+
+		// Return a mock response
+		response := `{
+			"facets": {
+				"count": 10
+			},
+			"hits": {
+				"hits": [
+					{
+						"repo": {
+							"raw": "example/repo"
+						},
+						"path": {
+							"raw": "src/main.go"
+						},
+						"content": {
+							"snippet": "<table><tr><td><div class=\"lineno\">41</div></td><td><pre>// set up</pre></td></tr><tr><td><div class=\"lineno\">42</div></td><td><pre>x := <mark>foo bar&amp;baz</mark></pre></td></tr></table>"
+						}
+					}
+				]
+			}
+		}`
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	// Route the real client to the mock server through an injected HTTP client
+	target, err := url.Parse(server.URL)
+	assert.NoError(t, err)
+	client := grepapp.NewClient()
+	client.HTTPClient = &http.Client{Transport: rewriteTransport{target: target}}
+
+	opts := &grepapp.SearchOptions{
+		Query:         "foo bar&baz #1",
+		UseRegex:      true,
+		WholeWords:    false,
+		CaseSensitive: true,
+		RepoFilter:    "example/repo",
+		PathFilter:    "src/ünï+code",
+		LangFilter:    "go",
+	}
+
+	// Call the function under test
+	results, count, err := client.Page(context.Background(), opts, 1)
+
+	// Check the returned values
+	assert.NoError(t, err)
+	assert.NotNil(t, results)
+	assert.Equal(t, 10, count)
+	assert.Equal(t, 1, len(results.Hits))
+
+	// Check the hit data
+	hit := results.Hits[0]
+	assert.Equal(t, "example/repo", hit.Repo)
+	assert.Equal(t, "src/main.go", hit.Path)
+	assert.Equal(t, "Go", hit.Lang)
+	assert.Equal(t, []grepapp.Line{{
+		LineNumber: 42,
+		Text:       "x := " + grepapp.C_MARK + "foo bar&baz" + grepapp.C_RST,
+		Matches:    []grepapp.Span{{Start: 5, End: 16}},
+	}}, hit.Lines)
+}
+
+func TestPageURLEncodesParameters(t *testing.T) {
+	// Build the URL directly so no request or pagination delay is involved
+	opts := &grepapp.SearchOptions{
+		Query:         "foo bar&baz",
+		CaseSensitive: true,
+		RepoFilter:    "org/repo#1",
+		PathFilter:    "src/ünï+code",
+		LangFilter:    "C++,Go",
+	}
+
+	u, err := url.Parse(grepapp.NewClient().PageURL(opts, 2))
+	assert.NoError(t, err)
+	assert.Equal(t, "/api/search", u.Path)
+	assert.Empty(t, u.Fragment)
+
+	query := u.Query()
+	assert.Equal(t, "foo bar&baz", query.Get("q"))
+	assert.Equal(t, "2", query.Get("page"))
+	assert.Equal(t, "true", query.Get("case"))
+	assert.Equal(t, "org/repo#1", query.Get("f.repo.pattern"))
+	assert.Equal(t, "src/ünï+code", query.Get("f.path.pattern"))
+	assert.Equal(t, "C++,Go", query.Get("f.lang"))
+	assert.Empty(t, query.Get("regexp"))
+	assert.Empty(t, query.Get("words"))
+	assert.Empty(t, query.Get("per_page"))
+
+	opts.PerPage = 500
+	u, err = url.Parse(grepapp.NewClient().PageURL(opts, 2))
+	assert.NoError(t, err)
+	assert.Equal(t, strconv.Itoa(grepapp.MaxPerPage), u.Query().Get("per_page"))
+}
+
+func TestPageCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results, _, err := grepapp.NewClient().Page(ctx, &grepapp.SearchOptions{Query: "test"}, 1)
+	assert.Nil(t, results)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestSearchStopsWhenCountExhausted(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		hits := ""
+		for i := 0; i < 5; i++ {
+			if i > 0 {
+				hits += ","
+			}
+			hits += fmt.Sprintf(`{"repo": {"raw": "example/repo"}, "path": {"raw": "file%d.go"}, "content": {"snippet": "<mark>test</mark>"}}`, i)
+		}
+		fmt.Fprintf(w, `{"facets": {"count": 5}, "hits": {"hits": [%s]}}`, hits)
+	}))
+	defer server.Close()
+
+	results, err := newTestClient(server.URL).Search(context.Background(), &grepapp.SearchOptions{Query: "test", MaxPages: 100})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, requests)
+	assert.Len(t, results.Hits, 5)
+	assert.Equal(t, 5, results.Count)
+}
+
+func TestSearchWithoutMatchesFetchesOnePage(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, `{"facets": {"count": 0}, "hits": {"hits": []}}`)
+	}))
+	defer server.Close()
+
+	results, err := newTestClient(server.URL).Search(context.Background(), &grepapp.SearchOptions{Query: "test"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, requests)
+	body, err := json.Marshal(results)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"hits":[]}`, string(body))
+}
+
+func TestSearchRespectsMaxPages(t *testing.T) {
+	var pages []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		pages = append(pages, page)
+		fmt.Fprintf(w, `{"facets": {"count": 1000}, "hits": {"hits": [{"repo": {"raw": "example/repo"}, "path": {"raw": "page%s.go"}, "content": {"snippet": "<mark>test</mark>"}}]}}`, page)
+	}))
+	defer server.Close()
+	client := newTestClient(server.URL)
+
+	results, err := client.Search(context.Background(), &grepapp.SearchOptions{Query: "test", MaxPages: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "2", "3"}, pages)
+	assert.Len(t, results.Hits, 3)
+
+	pages = nil
+	_, err = client.Search(context.Background(), &grepapp.SearchOptions{Query: "test", MaxPages: 0})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1"}, pages)
+}
+
+func TestSearchFollowsServedPageSize(t *testing.T) {
+	for _, honored := range []bool{true, false} {
+		var pages []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			page := r.URL.Query().Get("page")
+			pages = append(pages, page)
+			perPage := 10
+			if honored {
+				perPage, _ = strconv.Atoi(r.URL.Query().Get("per_page"))
+			}
+			var hits []string
+			for i := range perPage {
+				hits = append(hits, fmt.Sprintf(`{"repo": {"raw": "example/repo"}, "path": {"raw": "page%s-%d.go"}}`, page, i))
+			}
+			fmt.Fprintf(w, `{"facets": {"count": 40}, "hits": {"hits": [%s]}}`, strings.Join(hits, ","))
+		}))
+		client := newTestClient(server.URL)
+
+		results, err := client.Search(context.Background(), &grepapp.SearchOptions{Query: "test", MaxPages: 10, PerPage: 20})
+		server.Close()
+		assert.NoError(t, err)
+		if honored {
+			assert.Equal(t, []string{"1", "2"}, pages)
+		} else {
+			assert.Equal(t, []string{"1", "2", "3", "4"}, pages)
+		}
+		assert.Len(t, results.Hits, 40)
+	}
+}
+
+func TestWalkStartsFromFirstPage(t *testing.T) {
+	var pages []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		pages = append(pages, page)
+		fmt.Fprintf(w, `{"facets": {"count": 4}, "hits": {"hits": [{"repo": {"raw": "example/repo"}, "path": {"raw": "page%s.go"}}]}}`, page)
+	}))
+	defer server.Close()
+	client := newTestClient(server.URL)
+
+	results, err := client.Search(context.Background(), &grepapp.SearchOptions{Query: "test", MaxPages: 10, FirstPage: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"3", "4"}, pages)
+	assert.Len(t, results.Hits, 2)
+}
+
+func TestWalkSkipsFailedPages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		if page == "2" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprintf(w, `{"facets": {"count": 3}, "hits": {"hits": [{"repo": {"raw": "example/repo"}, "path": {"raw": "page%s.go"}, "content": {"snippet": "<mark>test</mark>"}}]}}`, page)
+	}))
+	defer server.Close()
+
+	for _, concurrency := range []int{1, 2} {
+		client := newTestClient(server.URL)
+		client.Concurrency = concurrency
+		opts := &grepapp.SearchOptions{Query: "test", MaxPages: 5}
+
+		_, err := client.Search(context.Background(), opts)
+		assert.ErrorContains(t, err, "HTTP 500")
+
+		client.SkipFailedPages = true
+		var numbers []int
+		var failed []int
+		err = client.Walk(context.Background(), opts, func(page *grepapp.Page) error {
+			numbers = append(numbers, page.Number)
+			if page.Err != nil {
+				failed = append(failed, page.Number)
+				assert.Empty(t, page.Results.Hits)
+			}
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []int{1, 2, 3}, numbers, concurrency)
+		assert.Equal(t, []int{2}, failed, concurrency)
+
+		results, err := client.Search(context.Background(), opts)
+		assert.ErrorContains(t, err, "HTTP 500")
+		assert.Len(t, results.Hits, 2, "the pages that went through are kept")
+	}
+}
+
+func TestSearchKeepsPartialResults(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") != "1" {
+			cancel()
+			<-r.Context().Done()
+			return
+		}
+		_, _ = w.Write([]byte(`{"facets":{"count":30},"hits":{"hits":[{"repo":{"raw":"example/repo"},"path":{"raw":"main.go"},"content":{"snippet":"<mark>test</mark>"}}]}}`))
+	}))
+	defer server.Close()
+
+	results, err := newTestClient(server.URL).Search(ctx, &grepapp.SearchOptions{Query: "test", MaxPages: 5})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 30, results.Count)
+	assert.Equal(t, []string{"main.go"}, []string{results.Hits[0].Path})
+}
+
+func TestWalkConcurrentKeepsPageOrder(t *testing.T) {
+	var inFlight, maxInFlight, requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		page := r.URL.Query().Get("page")
+		// Later pages answer first to shake up completion order
+		pageNum, _ := strconv.Atoi(page)
+		time.Sleep(time.Duration(12-pageNum) * 2 * time.Millisecond)
+		fmt.Fprintf(w, `{"facets": {"count": 10}, "hits": {"hits": [{"repo": {"raw": "example/repo"}, "path": {"raw": "page%s.go"}, "content": {"snippet": "<mark>test</mark>"}}]}}`, page)
+	}))
+	defer server.Close()
+	client := newTestClient(server.URL)
+	client.Concurrency = 4
+
+	var visited []int
+	err := client.Walk(context.Background(), &grepapp.SearchOptions{Query: "test", MaxPages: 100}, func(page *grepapp.Page) error {
+		visited = append(visited, page.Number)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, visited)
+	assert.EqualValues(t, 10, requests.Load())
+	assert.Greater(t, maxInFlight.Load(), int32(1))
+}
+
+func TestPageRetriesTransientFailures(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch requests {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			fmt.Fprint(w, `{"facets": {"count": 1}, "hits": {"hits": [{"repo": {"raw": "example/repo"}, "path": {"raw": "main.go"}, "content": {"snippet": "<mark>test</mark>"}}]}}`)
+		}
+	}))
+	defer server.Close()
+	client := newTestClient(server.URL)
+	opts := &grepapp.SearchOptions{Query: "test"}
+
+	results, count, err := client.Page(context.Background(), opts, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, requests)
+	assert.Equal(t, 1, count)
+	assert.Len(t, results.Hits, 1)
+
+	requests = 0
+	client.Retries = 1
+	_, _, err = client.Page(context.Background(), opts, 1)
+	assert.EqualError(t, err, fmt.Sprintf("grep.app rate limited: HTTP 429 %s/api/search?page=1&q=test", server.URL))
+	assert.Equal(t, 2, requests)
+}
+
+func TestPageRateLimited(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	client := newTestClient(server.URL)
+
+	_, err := client.Search(context.Background(), &grepapp.SearchOptions{Query: "test"})
+	assert.ErrorIs(t, err, grepapp.ErrRateLimited)
+	assert.Equal(t, client.Retries+1, requests)
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	_, err = client.Search(context.Background(), &grepapp.SearchOptions{Query: "test"})
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, grepapp.ErrRateLimited)
+}
+
+func TestPageErrorIncludesBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(w, `{"error": "invalid f.lang"}`)
+		fmt.Fprint(w, strings.Repeat("x", 10<<10))
+	}))
+	defer server.Close()
+	client := newTestClient(server.URL)
+
+	_, _, err := client.Page(context.Background(), &grepapp.SearchOptions{Query: "test"}, 1)
+	assert.ErrorContains(t, err, fmt.Sprintf(`HTTP 400 %s/api/search?page=1&q=test: {"error": "invalid f.lang"}`, server.URL))
+	assert.Less(t, len(err.Error()), 5<<10)
+}
+
+// snippetServer serves a single example/repo main.go hit with the given snippet.
+func snippetServer(snippet string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := json.Marshal(map[string]any{
+			"facets": map[string]any{"count": 1},
+			"hits": map[string]any{"hits": []any{map[string]any{
+				"repo":    map[string]any{"raw": "example/repo"},
+				"path":    map[string]any{"raw": "main.go"},
+				"content": map[string]any{"snippet": snippet},
+			}}},
+		})
+		_, _ = w.Write(body)
+	}))
+}
+
+func TestPageParsesLineNumbers(t *testing.T) {
+	snippet := `<table class="highlight-table">` +
+		`<tr data-line="41"><td><div class="lineno">41</div></td><td><div class="highlight"><pre>func f() {</pre></div></td></tr>` +
+		`<tr data-line="42"><td><div class="lineno">42</div></td><td><div class="highlight"><pre>	<mark>test</mark>()</pre></div></td></tr>` +
+		`<tr data-line="43"><td><div class="lineno">43</div></td><td><div class="highlight"><pre>	<mark>test</mark>()</pre></div></td></tr>` +
+		`</table>`
+	server := snippetServer(snippet)
+	defer server.Close()
+
+	results, _, err := newTestClient(server.URL).Page(context.Background(), &grepapp.SearchOptions{Query: "test"}, 1)
+	assert.NoError(t, err)
+	assert.Len(t, results.Hits, 1)
+
+	// Identical lines are kept apart by their line numbers
+	lines := results.Hits[0].Lines
+	assert.Len(t, lines, 2)
+	assert.Equal(t, 42, lines[0].LineNumber)
+	assert.Equal(t, 43, lines[1].LineNumber)
+	assert.Equal(t, lines[0].Text, lines[1].Text)
+}
+
+func TestSearchOutputIsDeterministic(t *testing.T) {
+	var rows string
+	for _, n := range []int{9, 3, 7, 1, 5} {
+		rows += fmt.Sprintf(`<tr><td><div class="lineno">%d</div></td><td><pre>line %d <mark>test</mark></pre></td></tr>`, n, n)
+	}
+	server := snippetServer("<table>" + rows + "</table>")
+	defer server.Close()
+	client := newTestClient(server.URL)
+
+	var outputs [][]byte
+	for i := 0; i < 2; i++ {
+		results, err := client.Search(context.Background(), &grepapp.SearchOptions{Query: "test"})
+		assert.NoError(t, err)
+		out, err := json.Marshal(results)
+		assert.NoError(t, err)
+		outputs = append(outputs, out)
+
+		var lineNums []int
+		for _, line := range results.Hits[0].Lines {
+			lineNums = append(lineNums, line.LineNumber)
+		}
+		assert.Equal(t, []int{1, 3, 5, 7, 9}, lineNums)
+	}
+	assert.Equal(t, outputs[0], outputs[1])
+}
+
+func TestPageDecodesGzipResponses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "gzip", r.Header.Get("Accept-Encoding"))
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		_, _ = gz.Write([]byte(`{"facets":{"count":1},"hits":{"hits":[{"repo":{"raw":"example/repo"},"path":{"raw":"main.go"},` +
+			`"content":{"snippet":"<table><tr><td><div class=\"lineno\">7</div></td><td><pre><mark>test</mark></pre></td></tr></table>"}}]}}`))
+		_ = gz.Close()
+	}))
+	defer server.Close()
+
+	results, count, err := newTestClient(server.URL).Page(context.Background(), &grepapp.SearchOptions{Query: "test"}, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, 7, results.Hits[0].Lines[0].LineNumber)
+}
+
+func TestPageDecodesHTMLEntities(t *testing.T) {
+	server := snippetServer(`<table><tr><td><div class="lineno">1</div></td><td><pre>if a &amp;&amp; b &lt;<mark>test</mark>&gt; &quot;x&quot;</pre></td></tr></table>`)
+	defer server.Close()
+
+	results, _, err := newTestClient(server.URL).Page(context.Background(), &grepapp.SearchOptions{Query: "test"}, 1)
+	assert.NoError(t, err)
+	text := results.Hits[0].Lines[0].Text
+	assert.Contains(t, text, "if a && b <")
+	assert.Contains(t, text, `> "x"`)
+	assert.NotContains(t, text, "&amp;")
+}
+
+func TestPageNormalizesRepos(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"facets":{"count":3},"hits":{"hits":[` +
+			`{"repo":{"raw":"https://github.com/example/repo.git"},"path":{"raw":"main.go"}},` +
+			`{"repo":{"raw":"gitlab.com/group/repo"},"path":{"raw":"main.go"}},` +
+			`{"repo":{"raw":"gitlab.com/group/repo"},"path":{"raw":"lib.go"}}]}}`))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	client := newTestClient(server.URL)
+	client.Logger = slog.New(slog.NewTextHandler(&buf, nil))
+	results, _, err := client.Page(context.Background(), &grepapp.SearchOptions{Query: "test"}, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "example/repo", results.Hits[0].Repo)
+	assert.Equal(t, "gitlab.com/group/repo", results.Hits[1].Repo)
+	assert.Equal(t, 1, strings.Count(buf.String(), `level=INFO msg="unexpected repository slug" page=1 repo=gitlab.com/group/repo`))
+}
+
+func TestPageLogsRequests(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"facets":{"count":0},"hits":{"hits":[]}}`))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	client := newTestClient(server.URL)
+	client.Logger = slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	_, _, err := client.Page(context.Background(), &grepapp.SearchOptions{Query: "test"}, 3)
+	assert.NoError(t, err)
+
+	logs := buf.String()
+	assert.Contains(t, logs, `level=DEBUG msg="retrying request"`)
+	assert.Contains(t, logs, "attempt=1 status=503")
+	assert.Contains(t, logs, `level=INFO msg="page fetched" page=3`)
+	assert.Contains(t, logs, "status=200 hits=0 count=0 latency=")
+}
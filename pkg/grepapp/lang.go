@@ -0,0 +1,99 @@
+package grepapp
+
+import (
+	"path"
+	"strings"
+)
+
+// Languages lists the language names accepted by grep.app's language filter.
+var Languages = []string{
+	"ActionScript", "Ada", "Apex", "AppleScript", "Assembly", "Astro", "Awk",
+	"Batchfile", "C", "C#", "C++", "CMake", "COBOL", "CSS", "CSV", "Clojure",
+	"CoffeeScript", "Common Lisp", "Crystal", "Cuda", "D", "Dart", "Diff",
+	"Dockerfile", "Elixir", "Elm", "Emacs Lisp", "Erlang", "F#", "Fortran",
+	"GLSL", "Go", "GraphQL", "Groovy", "HCL", "HTML", "Haskell", "INI",
+	"JSON", "Java", "JavaScript", "Julia", "Jupyter Notebook", "Kotlin",
+	"LLVM", "Lua", "Makefile", "Markdown", "Matlab", "Nim", "Nix", "OCaml",
+	"Objective-C", "Objective-C++", "PHP", "Pascal", "Perl", "PowerShell",
+	"Prolog", "Protocol Buffer", "Python", "R", "Racket", "Ruby", "Rust",
+	"SCSS", "SQL", "Sass", "Scala", "Scheme", "Shell", "Solidity", "Svelte",
+	"Swift", "TOML", "TSX", "Tcl", "TeX", "Text", "TypeScript", "V", "VHDL",
+	"Verilog", "Vim Script", "Vue", "XML", "YAML", "Zig",
+}
+
+// extLanguages maps file extensions to the language names grep.app uses in
+// its language filter.
+var extLanguages = map[string]string{
+	".c":     "C",
+	".h":     "C",
+	".cc":    "C++",
+	".cpp":   "C++",
+	".cxx":   "C++",
+	".hpp":   "C++",
+	".cs":    "C#",
+	".css":   "CSS",
+	".dart":  "Dart",
+	".ex":    "Elixir",
+	".exs":   "Elixir",
+	".erl":   "Erlang",
+	".go":    "Go",
+	".hs":    "Haskell",
+	".html":  "HTML",
+	".htm":   "HTML",
+	".java":  "Java",
+	".js":    "JavaScript",
+	".jsx":   "JavaScript",
+	".mjs":   "JavaScript",
+	".cjs":   "JavaScript",
+	".json":  "JSON",
+	".kt":    "Kotlin",
+	".kts":   "Kotlin",
+	".lua":   "Lua",
+	".md":    "Markdown",
+	".m":     "Objective-C",
+	".mm":    "Objective-C++",
+	".php":   "PHP",
+	".pl":    "Perl",
+	".pm":    "Perl",
+	".proto": "Protocol Buffer",
+	".py":    "Python",
+	".r":     "R",
+	".rb":    "Ruby",
+	".rs":    "Rust",
+	".scala": "Scala",
+	".scss":  "SCSS",
+	".sh":    "Shell",
+	".bash":  "Shell",
+	".zsh":   "Shell",
+	".sql":   "SQL",
+	".swift": "Swift",
+	".tf":    "HCL",
+	".toml":  "TOML",
+	".ts":    "TypeScript",
+	".tsx":   "TSX",
+	".vue":   "Vue",
+	".xml":   "XML",
+	".yaml":  "YAML",
+	".yml":   "YAML",
+	".zig":   "Zig",
+}
+
+// nameLanguages maps well known file names without a telling extension.
+var nameLanguages = map[string]string{
+	"dockerfile":     "Dockerfile",
+	"makefile":       "Makefile",
+	"gnumakefile":    "Makefile",
+	"cmakelists.txt": "CMake",
+	"gemfile":        "Ruby",
+	"rakefile":       "Ruby",
+}
+
+// languageOf guesses the language of a file from its name, returning an
+// empty string when it is not recognized.
+func languageOf(filePath string) string {
+	name := strings.ToLower(path.Base(filePath))
+	if lang, ok := nameLanguages[name]; ok {
+		return lang
+	}
+	return extLanguages[path.Ext(name)]
+}
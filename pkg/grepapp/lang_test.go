@@ -0,0 +1,39 @@
+package grepapp_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLanguageOf(t *testing.T) {
+	tests := map[string]string{
+		"main.go":               "Go",
+		"src/App.TSX":           "TSX",
+		"lib/foo.py":            "Python",
+		"build/Dockerfile":      "Dockerfile",
+		"Makefile":              "Makefile",
+		"docs/notes.unknownext": "",
+		"LICENSE":               "",
+	}
+	for path, lang := range tests {
+		assert.Equal(t, lang, grepapp.LanguageOf(path), path)
+	}
+}
+
+func TestLanguageOfReturnsKnownLanguages(t *testing.T) {
+	for _, path := range []string{"a.c", "a.cpp", "a.go", "a.proto", "a.tf", "Dockerfile", "CMakeLists.txt", "Gemfile"} {
+		assert.Contains(t, grepapp.Languages, grepapp.LanguageOf(path), path)
+	}
+}
+
+func TestPageSetsLanguage(t *testing.T) {
+	server := snippetServer(`<table><tr><td><div class="lineno">1</div></td><td><pre><mark>test</mark></pre></td></tr></table>`)
+	defer server.Close()
+
+	results, _, err := newTestClient(server.URL).Page(context.Background(), &grepapp.SearchOptions{Query: "test"}, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "Go", results.Hits[0].Lang)
+}
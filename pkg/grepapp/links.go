@@ -0,0 +1,75 @@
+package grepapp
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// slugRe matches owner/name repository slugs as used by GitHub.
+var slugRe = regexp.MustCompile(`^[A-Za-z0-9](?:[A-Za-z0-9-]*[A-Za-z0-9])?/[A-Za-z0-9._-]+$`)
+
+// NormalizeRepo turns the forms a GitHub repository may be given in, such
+// as https://github.com/owner/name.git, into an owner/name slug. It reports
+// whether the result is such a slug; other repositories are returned
+// trimmed, and get no GitHub links.
+func NormalizeRepo(repo string) (string, bool) {
+	repo = strings.TrimSpace(repo)
+	for _, prefix := range []string{"https://", "http://", "www.", "github.com/"} {
+		repo = strings.TrimPrefix(repo, prefix)
+	}
+	repo = strings.TrimSuffix(strings.Trim(repo, "/"), ".git")
+	return repo, slugRe.MatchString(repo)
+}
+
+// GithubURL returns a link to line of the file at path in repo on GitHub's
+// default branch, or an empty string if repo is not an owner/name slug. Line
+// 0 links to the file itself.
+func GithubURL(repo, path string, line int) string {
+	if !slugRe.MatchString(repo) {
+		return ""
+	}
+	link := fmt.Sprintf("https://github.com/%s/blob/HEAD/%s", repo, escapePath(path))
+	if line > 0 {
+		link += fmt.Sprintf("#L%d", line)
+	}
+	return link
+}
+
+// RawURL returns the address of the raw content of the file at path in repo
+// on GitHub's default branch, or an empty string if repo is not an
+// owner/name slug.
+func RawURL(repo, path string) string {
+	if !slugRe.MatchString(repo) {
+		return ""
+	}
+	return fmt.Sprintf("https://raw.githubusercontent.com/%s/HEAD/%s", repo, escapePath(path))
+}
+
+// RepoAPIURL returns the address of repo in the GitHub REST API, or an
+// empty string if repo is not an owner/name slug.
+func RepoAPIURL(repo string) string {
+	if !slugRe.MatchString(repo) {
+		return ""
+	}
+	return "https://api.github.com/repos/" + repo
+}
+
+// CloneURL returns the HTTPS clone address of repo on GitHub, or an empty
+// string if repo is not an owner/name slug.
+func CloneURL(repo string) string {
+	if !slugRe.MatchString(repo) {
+		return ""
+	}
+	return "https://github.com/" + repo + ".git"
+}
+
+// escapePath escapes each segment of a slash separated path for use in a URL.
+func escapePath(path string) string {
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,54 @@
+package grepapp_test
+
+import (
+	"testing"
+
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeRepo(t *testing.T) {
+	for raw, want := range map[string]string{
+		"example/repo":                         "example/repo",
+		" example/repo.js ":                    "example/repo.js",
+		"github.com/example/repo":              "example/repo",
+		"https://github.com/example/repo.git/": "example/repo",
+		"http://www.github.com/example/repo":   "example/repo",
+	} {
+		repo, ok := grepapp.NormalizeRepo(raw)
+		assert.Equal(t, want, repo, raw)
+		assert.True(t, ok, raw)
+	}
+	for raw, want := range map[string]string{
+		"gitlab.com/group/repo": "gitlab.com/group/repo",
+		"repo":                  "repo",
+		" -bad/repo":            "-bad/repo",
+	} {
+		repo, ok := grepapp.NormalizeRepo(raw)
+		assert.Equal(t, want, repo, raw)
+		assert.False(t, ok, raw)
+	}
+}
+
+func TestGithubURL(t *testing.T) {
+	assert.Equal(t, "https://github.com/example/repo/blob/HEAD/cmd/main.go#L42", grepapp.GithubURL("example/repo", "cmd/main.go", 42))
+	assert.Equal(t, "https://github.com/example/repo.js/blob/HEAD/docs/my%20notes%23.md", grepapp.GithubURL("example/repo.js", "docs/my notes#.md", 0))
+	assert.Empty(t, grepapp.GithubURL("gitlab.com/example/repo", "main.go", 1))
+	assert.Empty(t, grepapp.GithubURL("repo", "main.go", 1))
+	assert.Empty(t, grepapp.GithubURL("-bad/repo", "main.go", 1))
+}
+
+func TestRawURL(t *testing.T) {
+	assert.Equal(t, "https://raw.githubusercontent.com/example/repo/HEAD/docs/my%20notes%23.md", grepapp.RawURL("example/repo", "/docs/my notes#.md"))
+	assert.Empty(t, grepapp.RawURL("gitlab.com/example/repo", "main.go"))
+}
+
+func TestRepoAPIURL(t *testing.T) {
+	assert.Equal(t, "https://api.github.com/repos/example/repo.go", grepapp.RepoAPIURL("example/repo.go"))
+	assert.Empty(t, grepapp.RepoAPIURL("gitlab.com/example/repo"))
+}
+
+func TestCloneURL(t *testing.T) {
+	assert.Equal(t, "https://github.com/example/repo.go.git", grepapp.CloneURL("example/repo.go"))
+	assert.Empty(t, grepapp.CloneURL("gitlab.com/example/repo"))
+}
@@ -0,0 +1,70 @@
+package grepapp
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// DelayStep is how much a rate limited response raises the page delay
+	// when it is adaptive.
+	DelayStep = 500 * time.Millisecond
+	// DelayRecovery is how many pages in a row must be fetched without
+	// being rate limited to lower the page delay by DelayStep again.
+	DelayRecovery = 10
+)
+
+// pacer holds the part of the page delay added in response to rate
+// limiting, shared by every walk and worker of a client.
+type pacer struct {
+	mu        sync.Mutex
+	extra     time.Duration
+	successes int
+}
+
+// adaptive reports whether the page delay adapts to rate limiting.
+func (c *Client) adaptive() bool {
+	return c.MaxPageDelay > c.PageDelay
+}
+
+// pageDelay returns the delay to wait before the next page request.
+func (c *Client) pageDelay() time.Duration {
+	if !c.adaptive() {
+		return c.PageDelay
+	}
+	c.pacer.mu.Lock()
+	defer c.pacer.mu.Unlock()
+	return c.PageDelay + c.pacer.extra
+}
+
+// rateLimited raises the page delay by DelayStep, up to MaxPageDelay.
+func (c *Client) rateLimited() {
+	if !c.adaptive() {
+		return
+	}
+	c.pacer.mu.Lock()
+	defer c.pacer.mu.Unlock()
+	c.pacer.successes = 0
+	c.pacer.extra = min(c.pacer.extra+DelayStep, c.MaxPageDelay-c.PageDelay)
+	c.logger().Debug("raising page delay", "delay", c.PageDelay+c.pacer.extra)
+}
+
+// pageFetched lowers the page delay by DelayStep, down to PageDelay, once
+// DelayRecovery pages in a row were fetched without being rate limited.
+func (c *Client) pageFetched() {
+	if !c.adaptive() {
+		return
+	}
+	c.pacer.mu.Lock()
+	defer c.pacer.mu.Unlock()
+	if c.pacer.extra == 0 {
+		return
+	}
+	c.pacer.successes++
+	if c.pacer.successes < DelayRecovery {
+		return
+	}
+	c.pacer.successes = 0
+	c.pacer.extra = max(c.pacer.extra-DelayStep, 0)
+	c.logger().Debug("lowering page delay", "delay", c.PageDelay+c.pacer.extra)
+}
@@ -0,0 +1,69 @@
+package grepapp_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPageDelayAdaptsToRateLimiting(t *testing.T) {
+	limited := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if limited > 0 {
+			limited--
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, `{"facets": {"count": 1}, "hits": {"hits": []}}`)
+	}))
+	defer server.Close()
+	client := newTestClient(server.URL)
+	client.PageDelay = 100 * time.Millisecond
+	client.MaxPageDelay = 2 * time.Second
+	opts := &grepapp.SearchOptions{Query: "test"}
+	page := func() {
+		_, _, err := client.Page(context.Background(), opts, 1)
+		assert.NoError(t, err)
+	}
+
+	limited = 2
+	page()
+	assert.Equal(t, 100*time.Millisecond+2*grepapp.DelayStep, client.CurrentPageDelay())
+
+	for range grepapp.DelayRecovery - 1 {
+		page()
+	}
+	assert.Equal(t, 100*time.Millisecond+grepapp.DelayStep, client.CurrentPageDelay())
+	for range grepapp.DelayRecovery {
+		page()
+	}
+	assert.Equal(t, 100*time.Millisecond, client.CurrentPageDelay())
+
+	// The delay never goes past MaxPageDelay
+	limited = 3
+	page()
+	limited = 3
+	page()
+	assert.Equal(t, 2*time.Second, client.CurrentPageDelay())
+}
+
+func TestPageDelayIsFixedWithoutMaximum(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	client := newTestClient(server.URL)
+	client.PageDelay = 100 * time.Millisecond
+
+	_, _, err := client.Page(context.Background(), &grepapp.SearchOptions{Query: "test"}, 1)
+	assert.Error(t, err)
+	assert.Equal(t, 100*time.Millisecond, client.CurrentPageDelay())
+}
@@ -0,0 +1,173 @@
+package grepapp
+
+import (
+	"slices"
+	"sort"
+)
+
+type Line struct {
+	LineNumber int    `json:"line_number"`
+	Text       string `json:"text"`
+	// Context is set on unmatched lines kept around a match.
+	Context bool `json:"context,omitempty"`
+	// URL links to the line on GitHub when requested by the caller.
+	URL string `json:"url,omitempty"`
+	// Matches locates the highlighted parts of Text once its colors are
+	// stripped, in the order grep.app marked them.
+	Matches []Span `json:"matches,omitempty"`
+}
+
+// Span is a highlighted part of a line, as byte offsets into its plain
+// text, End being exclusive.
+type Span struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+type Result struct {
+	Repo string `json:"repo"`
+	Path string `json:"path"`
+	// Lang is the language of the file, empty when it is not known.
+	Lang string `json:"lang,omitempty"`
+	// URL links to the file on GitHub when requested by the caller.
+	URL string `json:"url,omitempty"`
+	// Lines holds the matched lines ordered by line number.
+	Lines []Line `json:"lines"`
+	// Truncated is set when matched lines were dropped to respect
+	// Results.MaxLines.
+	Truncated bool `json:"truncated,omitempty"`
+	// Snippet is the code grep.app returned for the file as plain text, one
+	// line per source line, when requested with SearchOptions.RawSnippet.
+	Snippet string `json:"snippet,omitempty"`
+	// Terms lists the queries that matched the file when the caller tags
+	// results of several searches merged together.
+	Terms []string `json:"terms,omitempty"`
+	// Stars is the number of stars of the repository on GitHub when looked
+	// up by the caller.
+	Stars *int `json:"stars,omitempty"`
+}
+
+type Results struct {
+	Hits []Result `json:"hits"`
+	// MaxLines caps the number of matched lines kept per file, keeping the
+	// lowest numbered ones along with their context lines. Zero means no
+	// cap.
+	MaxLines int `json:"-"`
+	// ContextAfter is the number of context lines kept after each match, so
+	// that MaxLines also drops those following the dropped matches.
+	ContextAfter int `json:"-"`
+	// Count is the number of files grep.app reported as matching the
+	// search, which may exceed the hits fetched.
+	Count int `json:"-"`
+}
+
+func (r *Results) AddHit(repo, path string, lineNum int, line string) {
+	r.hit(repo, path).addLine(Line{LineNumber: lineNum, Text: line}, r.MaxLines, r.ContextAfter)
+}
+
+func (r *Results) Merge(other *Results) {
+	for _, hit := range other.Hits {
+		merged := r.hit(hit.Repo, hit.Path)
+		if merged.Lang == "" {
+			merged.Lang = hit.Lang
+		}
+		merged.Truncated = merged.Truncated || hit.Truncated
+		if merged.Snippet == "" {
+			merged.Snippet = hit.Snippet
+		}
+		if merged.Stars == nil {
+			merged.Stars = hit.Stars
+		}
+		for _, term := range hit.Terms {
+			if !slices.Contains(merged.Terms, term) {
+				merged.Terms = append(merged.Terms, term)
+			}
+		}
+		for _, line := range hit.Lines {
+			merged.addLine(line, r.MaxLines, r.ContextAfter)
+		}
+	}
+}
+
+// LimitLines sets MaxLines and drops the lines already beyond it.
+func (r *Results) LimitLines(max int) {
+	r.MaxLines = max
+	for i := range r.Hits {
+		r.Hits[i].capMatches(max, r.ContextAfter)
+	}
+}
+
+// hit returns the result for repo and path, adding one without any lines
+// if the file has not been seen yet. The pointer is only valid until the
+// next call that adds a file.
+func (r *Results) hit(repo, path string) *Result {
+	for i := range r.Hits {
+		if r.Hits[i].Repo == repo && r.Hits[i].Path == path {
+			return &r.Hits[i]
+		}
+	}
+	r.Hits = append(r.Hits, Result{Repo: repo, Path: path, Lines: []Line{}})
+	return &r.Hits[len(r.Hits)-1]
+}
+
+// addLine inserts a line in order, keeping at most max matched lines unless
+// max is 0, along with the context lines up to after them. Snippets of the
+// same file can overlap between pages, so a line that is already present is
+// skipped and the first text recorded for it is kept, unless it was only
+// context and now matched.
+func (h *Result) addLine(line Line, max, after int) {
+	i := sort.Search(len(h.Lines), func(i int) bool {
+		return h.Lines[i].LineNumber >= line.LineNumber
+	})
+	if i < len(h.Lines) && h.Lines[i].LineNumber == line.LineNumber {
+		if h.Lines[i].Context && !line.Context {
+			h.Lines[i] = line
+			h.capMatches(max, after)
+		}
+		return
+	}
+	h.Lines = slices.Insert(h.Lines, i, line)
+	h.capMatches(max, after)
+}
+
+// capMatches keeps the lowest numbered max matched lines, unless max is 0.
+// Context lines are not counted: those before the last kept match are kept,
+// along with those up to after lines past it. Further context belongs to
+// the dropped matches.
+func (h *Result) capMatches(max, after int) {
+	if max <= 0 {
+		return
+	}
+	// Once truncated, context added later may belong to dropped matches
+	if n := h.MatchedLines(); n < max || n == max && !h.Truncated {
+		return
+	}
+	matched := 0
+	for i, line := range h.Lines {
+		if line.Context {
+			continue
+		}
+		if matched++; matched < max {
+			continue
+		}
+		end := i + 1
+		for end < len(h.Lines) && h.Lines[end].Context && h.Lines[end].LineNumber <= line.LineNumber+after {
+			end++
+		}
+		h.Lines = h.Lines[:end]
+		h.Truncated = true
+		return
+	}
+}
+
+// MatchedLines returns the number of matched lines of h, leaving out context
+// lines.
+func (h *Result) MatchedLines() int {
+	n := 0
+	for _, line := range h.Lines {
+		if !line.Context {
+			n++
+		}
+	}
+	return n
+}
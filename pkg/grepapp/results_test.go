@@ -0,0 +1,115 @@
+package grepapp_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeAddsNoPlaceholderLines(t *testing.T) {
+	page1 := &grepapp.Results{}
+	page1.AddHit("example/repo", "main.go", 3, "foo")
+	page2 := &grepapp.Results{Hits: []grepapp.Result{
+		{Repo: "example/repo", Path: "main.go", Lines: []grepapp.Line{{LineNumber: 8, Text: "bar"}}},
+		{Repo: "example/repo", Path: "README.md"},
+	}}
+
+	merged := &grepapp.Results{}
+	merged.Merge(page1)
+	merged.Merge(page2)
+
+	assert.Len(t, merged.Hits, 2)
+	assert.Equal(t, []grepapp.Line{{LineNumber: 3, Text: "foo"}, {LineNumber: 8, Text: "bar"}}, merged.Hits[0].Lines)
+	assert.Empty(t, merged.Hits[1].Lines)
+	for _, hit := range merged.Hits {
+		for _, line := range hit.Lines {
+			assert.NotZero(t, line.LineNumber)
+			assert.NotEmpty(t, line.Text)
+		}
+	}
+}
+
+func TestDuplicateLinesAreKeptOnce(t *testing.T) {
+	hits := &grepapp.Results{}
+	hits.AddHit("example/repo", "main.go", 3, "foo")
+	hits.AddHit("example/repo", "main.go", 3, "foo")
+	hits.AddHit("example/repo", "main.go", 1, "bar")
+
+	page := &grepapp.Results{}
+	page.AddHit("example/repo", "main.go", 3, "foo")
+	page.AddHit("example/repo", "main.go", 1, "bar")
+	page.AddHit("other/repo", "main.go", 3, "foo")
+	hits.Merge(page)
+	hits.Merge(page)
+
+	assert.Len(t, hits.Hits, 2)
+	assert.Equal(t, []grepapp.Line{{LineNumber: 1, Text: "bar"}, {LineNumber: 3, Text: "foo"}}, hits.Hits[0].Lines)
+	assert.Equal(t, []grepapp.Line{{LineNumber: 3, Text: "foo"}}, hits.Hits[1].Lines)
+}
+
+func TestMaxLinesKeepsLowestNumberedLines(t *testing.T) {
+	hits := &grepapp.Results{MaxLines: 2}
+	hits.AddHit("example/repo", "main.go", 5, "e")
+	hits.AddHit("example/repo", "main.go", 3, "c")
+	hits.AddHit("example/repo", "other.go", 1, "a")
+	assert.False(t, hits.Hits[0].Truncated)
+
+	hits.AddHit("example/repo", "main.go", 9, "i")
+	hits.AddHit("example/repo", "main.go", 1, "a")
+	assert.Equal(t, []grepapp.Line{{LineNumber: 1, Text: "a"}, {LineNumber: 3, Text: "c"}}, hits.Hits[0].Lines)
+	assert.True(t, hits.Hits[0].Truncated)
+	assert.False(t, hits.Hits[1].Truncated)
+
+	page := &grepapp.Results{}
+	page.AddHit("example/repo", "other.go", 2, "b")
+	page.AddHit("example/repo", "other.go", 3, "c")
+	page.LimitLines(1)
+	assert.Equal(t, []grepapp.Line{{LineNumber: 2, Text: "b"}}, page.Hits[0].Lines)
+	assert.True(t, page.Hits[0].Truncated)
+
+	hits.Merge(page)
+	assert.Equal(t, []grepapp.Line{{LineNumber: 1, Text: "a"}, {LineNumber: 2, Text: "b"}}, hits.Hits[1].Lines)
+	assert.True(t, hits.Hits[1].Truncated)
+}
+
+func TestMaxLinesCountsOnlyMatchedLines(t *testing.T) {
+	// Context lines as -B 2 -A 1 leaves them around matches on 3 and 6
+	lines := []grepapp.Line{
+		{LineNumber: 1, Context: true},
+		{LineNumber: 2, Context: true},
+		{LineNumber: 3},
+		{LineNumber: 4, Context: true},
+		{LineNumber: 5, Context: true},
+		{LineNumber: 6},
+		{LineNumber: 7, Context: true},
+	}
+	numbers := func(lines []grepapp.Line) []int {
+		var numbers []int
+		for _, line := range lines {
+			numbers = append(numbers, line.LineNumber)
+		}
+		return numbers
+	}
+
+	page := &grepapp.Results{Hits: []grepapp.Result{{Repo: "example/repo", Path: "main.go", Lines: slices.Clone(lines)}}, ContextAfter: 1}
+	page.LimitLines(2)
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 6, 7}, numbers(page.Hits[0].Lines))
+	assert.False(t, page.Hits[0].Truncated)
+	page.LimitLines(1)
+	assert.Equal(t, []int{1, 2, 3, 4}, numbers(page.Hits[0].Lines), "line 5 is context of the dropped match")
+	assert.True(t, page.Hits[0].Truncated)
+
+	hits := &grepapp.Results{MaxLines: 1, ContextAfter: 1}
+	hits.Merge(&grepapp.Results{Hits: []grepapp.Result{{Repo: "example/repo", Path: "main.go", Lines: slices.Clone(lines)}}})
+	assert.Equal(t, []int{1, 2, 3, 4}, numbers(hits.Hits[0].Lines))
+	assert.True(t, hits.Hits[0].Truncated)
+}
+
+func TestMergeUnitesTerms(t *testing.T) {
+	results := &grepapp.Results{}
+	results.Merge(&grepapp.Results{Hits: []grepapp.Result{{Repo: "example/repo", Path: "main.go", Terms: []string{"foo"}}}})
+	results.Merge(&grepapp.Results{Hits: []grepapp.Result{{Repo: "example/repo", Path: "main.go", Terms: []string{"bar", "foo"}}}})
+	assert.Equal(t, []string{"foo", "bar"}, results.Hits[0].Terms)
+}
@@ -0,0 +1,110 @@
+package grepapp
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	rowRe    = regexp.MustCompile(`(?s)<tr[^>]*>(.*?)</tr>`)
+	linenoRe = regexp.MustCompile(`<div class="lineno"[^>]*>\s*(\d+)\s*</div>`)
+	preRe    = regexp.MustCompile(`(?s)<pre[^>]*>(.*?)</pre>`)
+	tagRe    = regexp.MustCompile(`<[^>]*>`)
+)
+
+type snippetLine struct {
+	number int
+	html   string
+}
+
+func (l snippetLine) matched() bool {
+	return strings.Contains(l.html, "<mark")
+}
+
+// snippetLines splits a grep.app snippet into its numbered source lines. The
+// snippet is an HTML table with one row per line, holding the line number in
+// a div.lineno and the highlighted code in a pre.
+func snippetLines(snippet string) []snippetLine {
+	var lines []snippetLine
+	rows := rowRe.FindAllStringSubmatch(snippet, -1)
+	if len(rows) == 0 {
+		// Without a table there are no line numbers, so count from the top
+		for i, line := range strings.Split(snippet, "\n") {
+			lines = append(lines, snippetLine{number: i + 1, html: line})
+		}
+		return lines
+	}
+
+	for _, row := range rows {
+		lineno := linenoRe.FindStringSubmatch(row[1])
+		if lineno == nil {
+			continue
+		}
+		number, err := strconv.Atoi(lineno[1])
+		if err != nil {
+			continue
+		}
+		html := row[1]
+		if pre := preRe.FindStringSubmatch(row[1]); pre != nil {
+			html = pre[1]
+		}
+		lines = append(lines, snippetLine{number: number, html: html})
+	}
+	return lines
+}
+
+// isMark reports whether tag opens a <mark>, with or without attributes.
+func isMark(tag string) bool {
+	return tag == "<mark>" || strings.HasPrefix(tag, "<mark ")
+}
+
+// highlight walks the tags of a snippet line in order, coloring each <mark>
+// span on its own and dropping the remaining markup.
+func highlight(line string) string {
+	var b strings.Builder
+	prev := 0
+	for _, tag := range tagRe.FindAllStringIndex(line, -1) {
+		// Entities are decoded between tags so escaped markup in the code is not mistaken for tags
+		b.WriteString(html.UnescapeString(line[prev:tag[0]]))
+		prev = tag[1]
+		switch name := line[tag[0]:tag[1]]; {
+		case isMark(name):
+			b.WriteString(C_MARK)
+		case name == "</mark>":
+			b.WriteString(C_RST)
+		}
+	}
+	b.WriteString(html.UnescapeString(line[prev:]))
+	return b.String()
+}
+
+// matchSpans locates the <mark> tags of a snippet line in its plain text.
+func matchSpans(line string) []Span {
+	var spans []Span
+	offset, start, prev := 0, -1, 0
+	for _, tag := range tagRe.FindAllStringIndex(line, -1) {
+		offset += len(html.UnescapeString(line[prev:tag[0]]))
+		prev = tag[1]
+		switch name := line[tag[0]:tag[1]]; {
+		case isMark(name):
+			start = offset
+		case name == "</mark>" && start >= 0:
+			spans = append(spans, Span{Start: start, End: offset})
+			start = -1
+		}
+	}
+	return spans
+}
+
+// plainSnippet returns the source lines of a snippet as plain text, without
+// markup or line numbers.
+func plainSnippet(snippet string) string {
+	lines := snippetLines(snippet)
+	text := make([]string, len(lines))
+	for i, line := range lines {
+		text[i] = html.UnescapeString(tagRe.ReplaceAllString(line.html, ""))
+	}
+	return strings.Join(text, "\n")
+}
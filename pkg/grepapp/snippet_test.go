@@ -0,0 +1,142 @@
+package grepapp_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+	"github.com/stretchr/testify/assert"
+)
+
+// benchmarkResponse builds a page shaped like a real grep.app response: ten
+// hits, each with a snippet of a dozen lines and a few highlighted matches.
+func benchmarkResponse() []byte {
+	var hits []any
+	for i := 0; i < 10; i++ {
+		var rows strings.Builder
+		rows.WriteString(`<table class="highlight-table">`)
+		for n := 1; n <= 12; n++ {
+			code := fmt.Sprintf(`<span class="hl-k">if</span> err := doSomething(ctx, &amp;opts%d); err != nil {`, n)
+			if n%4 == 0 {
+				code = fmt.Sprintf(`	<span class="hl-k">return</span> <mark>fmt.Errorf</mark>(&quot;step %d: %%w&quot;, err)`, n)
+			}
+			fmt.Fprintf(&rows, `<tr data-line="%d"><td><div class="lineno">%d</div></td><td><div class="highlight"><pre>%s</pre></div></td></tr>`, n, n, code)
+		}
+		rows.WriteString(`</table>`)
+		hits = append(hits, map[string]any{
+			"repo":    map[string]any{"raw": fmt.Sprintf("example/repo%d", i)},
+			"path":    map[string]any{"raw": fmt.Sprintf("pkg/file%d.go", i)},
+			"content": map[string]any{"snippet": rows.String()},
+		})
+	}
+	body, _ := json.Marshal(map[string]any{
+		"facets": map[string]any{"count": 1000},
+		"hits":   map[string]any{"hits": hits},
+	})
+	return body
+}
+
+func BenchmarkDecodeResults(b *testing.B) {
+	body := benchmarkResponse()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		results, _, err := grepapp.DecodeResults(bytes.NewReader(body), &grepapp.SearchOptions{})
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(results.Hits) != 10 {
+			b.Fatalf("got %d hits, want 10", len(results.Hits))
+		}
+	}
+}
+
+func TestDecodeResultsKeepsContextLines(t *testing.T) {
+	var rows strings.Builder
+	for n := 1; n <= 8; n++ {
+		code := fmt.Sprintf("line %d", n)
+		if n == 3 || n == 6 {
+			code = "<mark>test</mark>"
+		}
+		fmt.Fprintf(&rows, `<tr><td><div class="lineno">%d</div></td><td><pre>%s</pre></td></tr>`, n, code)
+	}
+	body, _ := json.Marshal(map[string]any{
+		"facets": map[string]any{"count": 1},
+		"hits": map[string]any{"hits": []any{map[string]any{
+			"repo":    map[string]any{"raw": "example/repo"},
+			"path":    map[string]any{"raw": "main.go"},
+			"content": map[string]any{"snippet": "<table>" + rows.String() + "</table>"},
+		}}},
+	})
+
+	results, _, err := grepapp.DecodeResults(bytes.NewReader(body), &grepapp.SearchOptions{ContextBefore: 1, ContextAfter: 2})
+	assert.NoError(t, err)
+	var numbers, context []int
+	for _, line := range results.Hits[0].Lines {
+		numbers = append(numbers, line.LineNumber)
+		if line.Context {
+			context = append(context, line.LineNumber)
+		}
+	}
+	assert.Equal(t, []int{2, 3, 4, 5, 6, 7, 8}, numbers)
+	assert.Equal(t, []int{2, 4, 5, 7, 8}, context)
+}
+
+func TestDecodeResultsRawSnippet(t *testing.T) {
+	snippet := `<table><tr><td><div class="lineno">1</div></td><td><pre>if a &amp;&amp; b {</pre></td></tr>` +
+		`<tr><td><div class="lineno">2</div></td><td><pre>	<mark>test</mark>()</pre></td></tr></table>`
+	body, _ := json.Marshal(map[string]any{
+		"hits": map[string]any{"hits": []any{map[string]any{
+			"repo":    map[string]any{"raw": "example/repo"},
+			"path":    map[string]any{"raw": "main.go"},
+			"content": map[string]any{"snippet": snippet},
+		}}},
+	})
+
+	results, _, err := grepapp.DecodeResults(bytes.NewReader(body), &grepapp.SearchOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, results.Hits[0].Snippet)
+
+	results, _, err = grepapp.DecodeResults(bytes.NewReader(body), &grepapp.SearchOptions{RawSnippet: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "if a && b {\n\ttest()", results.Hits[0].Snippet)
+}
+
+func TestDecodeResultsMatchSpans(t *testing.T) {
+	snippet := `<table><tr><td><div class="lineno">4</div></td><td><pre>` +
+		`<span class="hl-k">if</span> a &amp;&amp; <mark>test</mark>(<mark class="hl">test</mark>) {</pre></td></tr></table>`
+	body, _ := json.Marshal(map[string]any{
+		"hits": map[string]any{"hits": []any{map[string]any{
+			"repo":    map[string]any{"raw": "example/repo"},
+			"path":    map[string]any{"raw": "main.go"},
+			"content": map[string]any{"snippet": snippet},
+		}}},
+	})
+
+	results, _, err := grepapp.DecodeResults(bytes.NewReader(body), &grepapp.SearchOptions{})
+	assert.NoError(t, err)
+	line := results.Hits[0].Lines[0]
+	assert.Equal(t, []grepapp.Span{{Start: 8, End: 12}, {Start: 13, End: 17}}, line.Matches)
+	plain := "if a && test(test) {"
+	assert.Equal(t, "test", plain[line.Matches[0].Start:line.Matches[0].End])
+	assert.Equal(t, "test", plain[line.Matches[1].Start:line.Matches[1].End])
+}
+
+func TestDecodeResultsHighlightsEachMark(t *testing.T) {
+	snippet := `<table><tr><td><div class="lineno">2</div></td><td><pre>` +
+		`<mark>foo</mark>(a, <mark class="hl">foo</mark>) &lt;mark&gt;</pre></td></tr></table>`
+	body, _ := json.Marshal(map[string]any{
+		"hits": map[string]any{"hits": []any{map[string]any{
+			"repo":    map[string]any{"raw": "example/repo"},
+			"path":    map[string]any{"raw": "main.go"},
+			"content": map[string]any{"snippet": snippet},
+		}}},
+	})
+
+	results, _, err := grepapp.DecodeResults(bytes.NewReader(body), &grepapp.SearchOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, grepapp.C_MARK+"foo"+grepapp.C_RST+"(a, "+grepapp.C_MARK+"foo"+grepapp.C_RST+") <mark>", results.Hits[0].Lines[0].Text)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+	"golang.org/x/term"
+)
+
+// progress keeps a single status line about a running scan up to date,
+// rewriting it in place. A nil progress reports nothing.
+type progress struct {
+	w        io.Writer
+	maxPages int
+	lastPage int
+	shown    bool
+}
+
+// newProgress returns a progress writing to stderr, or nil when stderr is not
+// a terminal, would be shared with -verbose logs or -quiet is given.
+func newProgress(args *Arguments) *progress {
+	if args.Quiet || args.Verbose > 0 || !term.IsTerminal(int(os.Stderr.Fd())) {
+		return nil
+	}
+	return &progress{w: os.Stderr, maxPages: args.MaxPages}
+}
+
+// estimate derives the number of pages from the count and size of the first
+// page. It must be called before the page is filtered.
+func (p *progress) estimate(page *grepapp.Page) {
+	if p == nil || page.Number != 1 {
+		return
+	}
+	p.lastPage = min(max(p.maxPages, 1), grepapp.MaxPages)
+	if perPage := len(page.Results.Hits); perPage > 0 {
+		p.lastPage = min(p.lastPage, (page.Count+perPage-1)/perPage)
+	}
+}
+
+// update shows that page was fetched and hits files matched so far.
+func (p *progress) update(page, hits int) {
+	if p == nil {
+		return
+	}
+	fmt.Fprintf(p.w, "\r\033[Kpage %d of %d (%d hits so far)", page, max(p.lastPage, page), hits)
+	p.shown = true
+}
+
+// clear removes the status line so that other output can be written.
+func (p *progress) clear() {
+	if p == nil || !p.shown {
+		return
+	}
+	fmt.Fprint(p.w, "\r\033[K")
+	p.shown = false
+}
@@ -0,0 +1,28 @@
+package main_test
+
+import (
+	"bytes"
+	"testing"
+
+	grepgithub "github.com/aviadhahami/grepgithub-go"
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgress(t *testing.T) {
+	var buf bytes.Buffer
+	p := grepgithub.NewProgress(&buf, 100)
+
+	first := &grepapp.Results{}
+	first.AddHit("example/repo", "a.go", 1, "foo")
+	first.AddHit("example/repo", "b.go", 1, "foo")
+	p.Estimate(&grepapp.Page{Number: 1, Results: first, Count: 5})
+	p.Update(1, 2)
+	p.Update(2, 4)
+	p.Clear()
+	p.Clear()
+
+	assert.Equal(t, "\r\x1b[Kpage 1 of 3 (2 hits so far)"+
+		"\r\x1b[Kpage 2 of 3 (4 hits so far)"+
+		"\r\x1b[K", buf.String())
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+)
+
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+// The types below cover the part of SARIF 2.1.0 that -format sarif writes.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// writeSARIF writes hits as a SARIF 2.1.0 log for code scanning tools. Each
+// query is a rule and each matched line a result located at repo/path and
+// its line number. Files without lines are located without a region.
+func writeSARIF(w io.Writer, hits *grepapp.Results, args *Arguments) error {
+	driver := sarifDriver{
+		Name:           "grepgithub",
+		Version:        version,
+		InformationURI: "https://github.com/aviadhahami/grepgithub-go",
+		Rules:          []sarifRule{},
+	}
+	for _, query := range args.queries() {
+		driver.Rules = append(driver.Rules, sarifRule{ID: query, ShortDescription: sarifMessage{Text: "Matches " + query}})
+	}
+
+	results := []sarifResult{}
+	for _, hit := range hits.Hits {
+		rule := args.queries()[0]
+		if len(hit.Terms) > 0 {
+			rule = hit.Terms[0]
+		}
+		result := func(message string, region *sarifRegion) sarifResult {
+			return sarifResult{
+				RuleID:  rule,
+				Level:   "warning",
+				Message: sarifMessage{Text: message},
+				Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: hit.Repo + "/" + hit.Path},
+					Region:           region,
+				}}},
+			}
+		}
+		matched := false
+		for _, line := range hit.Lines {
+			if line.Context {
+				continue
+			}
+			matched = true
+			results = append(results, result(stripANSI(line.Text), &sarifRegion{StartLine: line.LineNumber}))
+		}
+		if !matched {
+			results = append(results, result("Matches "+rule, nil))
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	if args.Pretty {
+		enc.SetIndent("", "  ")
+	}
+	return enc.Encode(sarifLog{
+		Schema:  sarifSchema,
+		Version: "2.1.0",
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	})
+}
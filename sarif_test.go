@@ -0,0 +1,46 @@
+package main_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	grepgithub "github.com/aviadhahami/grepgithub-go"
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// sarifSchemaFile is the official SARIF 2.1.0 schema published by OASIS,
+// including its errata.
+const sarifSchemaFile = "testdata/sarif-schema-2.1.0.json"
+
+func TestWriteSARIF(t *testing.T) {
+	hits := &grepapp.Results{}
+	hits.AddHit("example/repo", "main.go", 3, "foo("+grepapp.C_MARK+"x"+grepapp.C_RST+")")
+	hits.Merge(&grepapp.Results{Hits: []grepapp.Result{{Repo: "example/repo", Path: "main.go", Lines: []grepapp.Line{{LineNumber: 4, Text: "bar", Context: true}}}}})
+	hits.Merge(&grepapp.Results{Hits: []grepapp.Result{{Repo: "example/repo", Path: "README.md"}}})
+	args := &grepgithub.Arguments{}
+	args.Query = "foo"
+
+	var buf bytes.Buffer
+	assert.NoError(t, grepgithub.WriteSARIF(&buf, hits, args))
+	var log map[string]any
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &log))
+	schema, err := jsonschema.Compile(sarifSchemaFile)
+	assert.NoError(t, err)
+	assert.NoError(t, schema.Validate(log))
+
+	assert.Equal(t, "2.1.0", log["version"])
+	run := log["runs"].([]any)[0].(map[string]any)
+	assert.Equal(t, []any{map[string]any{"id": "foo", "shortDescription": map[string]any{"text": "Matches foo"}}}, run["tool"].(map[string]any)["driver"].(map[string]any)["rules"])
+	assert.Equal(t, []any{
+		map[string]any{"ruleId": "foo", "level": "warning", "message": map[string]any{"text": "foo(x)"}, "locations": []any{map[string]any{"physicalLocation": map[string]any{
+			"artifactLocation": map[string]any{"uri": "example/repo/main.go"},
+			"region":           map[string]any{"startLine": float64(3)},
+		}}}},
+		map[string]any{"ruleId": "foo", "level": "warning", "message": map[string]any{"text": "Matches foo"}, "locations": []any{map[string]any{"physicalLocation": map[string]any{
+			"artifactLocation": map[string]any{"uri": "example/repo/README.md"},
+		}}}},
+	}, run["results"])
+}
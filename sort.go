@@ -0,0 +1,70 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+)
+
+// sortKeys are the keys accepted by -sort. Files with the most matched lines
+// come first when sorting by lines, and the most starred repositories when
+// sorting by stars, repositories without stars last.
+var sortKeys = map[string]func(a, b *grepapp.Result) int{
+	"repo": func(a, b *grepapp.Result) int {
+		return cmp.Compare(a.Repo, b.Repo)
+	},
+	"path": func(a, b *grepapp.Result) int {
+		return cmp.Compare(a.Path, b.Path)
+	},
+	"lines": func(a, b *grepapp.Result) int {
+		return cmp.Compare(b.MatchedLines(), a.MatchedLines())
+	},
+	"stars": func(a, b *grepapp.Result) int {
+		return cmp.Compare(starCount(b), starCount(a))
+	},
+}
+
+func starCount(hit *grepapp.Result) int {
+	if hit.Stars == nil {
+		return -1
+	}
+	return *hit.Stars
+}
+
+// parseSort splits a comma separated list of sort keys. "none" keeps files in
+// the order grep.app returned them and is returned as an empty list.
+func parseSort(value string) ([]string, error) {
+	if value == "none" {
+		return []string{}, nil
+	}
+	var keys []string
+	for _, key := range strings.Split(value, ",") {
+		key = strings.TrimSpace(key)
+		if _, ok := sortKeys[key]; !ok {
+			return nil, fmt.Errorf("Unknown sort key %q, expected repo, path, lines, stars or none", key)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// sortHits orders files by keys, falling back to repo and path so that the
+// order does not depend on how pages arrived.
+func sortHits(hits *grepapp.Results, keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+	keys = append(slices.Clone(keys), "repo", "path")
+	sort.Slice(hits.Hits, func(i, j int) bool {
+		for _, key := range keys {
+			if c := sortKeys[key](&hits.Hits[i], &hits.Hits[j]); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+}
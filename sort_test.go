@@ -0,0 +1,59 @@
+package main_test
+
+import (
+	"testing"
+
+	grepgithub "github.com/aviadhahami/grepgithub-go"
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+	"github.com/stretchr/testify/assert"
+)
+
+func unsortedHits() *grepapp.Results {
+	hits := &grepapp.Results{}
+	hits.AddHit("b/repo", "z.go", 1, "foo")
+	hits.AddHit("a/repo", "y.go", 1, "foo")
+	hits.AddHit("b/repo", "a.go", 1, "foo")
+	hits.AddHit("b/repo", "a.go", 2, "foo")
+	hits.AddHit("a/repo", "x.go", 1, "foo")
+	hits.Merge(&grepapp.Results{Hits: []grepapp.Result{{Repo: "a/repo", Path: "x.go", Lines: []grepapp.Line{{LineNumber: 2, Text: "context", Context: true}, {LineNumber: 3, Text: "context", Context: true}}}}})
+	return hits
+}
+
+func TestSortHits(t *testing.T) {
+	tests := map[string][]string{
+		"":      {"a/repo/x.go", "a/repo/y.go", "b/repo/a.go", "b/repo/z.go"},
+		"path":  {"b/repo/a.go", "a/repo/x.go", "a/repo/y.go", "b/repo/z.go"},
+		"lines": {"b/repo/a.go", "a/repo/x.go", "a/repo/y.go", "b/repo/z.go"},
+		"none":  {"b/repo/z.go", "a/repo/y.go", "b/repo/a.go", "a/repo/x.go"},
+	}
+	for sort, want := range tests {
+		arguments := []string{"-q", "foo"}
+		if sort != "" {
+			arguments = append(arguments, "-sort", sort)
+		}
+		args, err := grepgithub.ParseArguments(arguments)
+		assert.NoError(t, err)
+
+		hits := unsortedHits()
+		grepgithub.SortHits(hits, args.Sort)
+		assert.Equal(t, want, paths(hits), sort)
+	}
+}
+
+func TestSortHitsByStars(t *testing.T) {
+	stars := func(n int) *int { return &n }
+	hits := unsortedHits()
+	hits.AddHit("c/repo", "w.go", 1, "foo")
+	hits.AddHit("d/repo", "v.go", 1, "foo")
+	for i := range hits.Hits {
+		switch hits.Hits[i].Repo {
+		case "a/repo", "c/repo":
+			hits.Hits[i].Stars = stars(10)
+		case "b/repo":
+			hits.Hits[i].Stars = stars(500)
+		}
+	}
+
+	grepgithub.SortHits(hits, []string{"stars"})
+	assert.Equal(t, []string{"b/repo/a.go", "b/repo/z.go", "a/repo/x.go", "a/repo/y.go", "c/repo/w.go", "d/repo/v.go"}, paths(hits))
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+	_ "modernc.org/sqlite"
+)
+
+// sqliteSchema creates the tables -format sql fills, unless a previous run
+// already did. Files and matches are keyed by location so that repeated
+// runs update them rather than adding duplicates, pointing them at the
+// latest run that found them.
+const sqliteSchema = `CREATE TABLE IF NOT EXISTS runs (
+  id INTEGER PRIMARY KEY,
+  query TEXT NOT NULL,
+  search TEXT NOT NULL,
+  started_at TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS repos (
+  name TEXT PRIMARY KEY,
+  stars INTEGER
+);
+CREATE TABLE IF NOT EXISTS files (
+  repo TEXT NOT NULL REFERENCES repos (name),
+  path TEXT NOT NULL,
+  lang TEXT,
+  run_id INTEGER NOT NULL REFERENCES runs (id),
+  PRIMARY KEY (repo, path)
+);
+CREATE TABLE IF NOT EXISTS matches (
+  repo TEXT NOT NULL,
+  path TEXT NOT NULL,
+  line_number INTEGER NOT NULL,
+  text TEXT NOT NULL,
+  run_id INTEGER NOT NULL REFERENCES runs (id),
+  PRIMARY KEY (repo, path, line_number),
+  FOREIGN KEY (repo, path) REFERENCES files (repo, path)
+);
+`
+
+// The statements that record a run and upsert its repositories, files and
+// matched lines, pointing them at the run.
+const (
+	insertRun   = "INSERT INTO runs (query, search, started_at) VALUES (?, ?, ?)"
+	upsertRepo  = "INSERT INTO repos (name, stars) VALUES (?, ?) ON CONFLICT (name) DO UPDATE SET stars = coalesce(excluded.stars, stars)"
+	upsertFile  = "INSERT INTO files (repo, path, lang, run_id) VALUES (?, ?, ?, ?) ON CONFLICT (repo, path) DO UPDATE SET lang = excluded.lang, run_id = excluded.run_id"
+	upsertMatch = "INSERT INTO matches (repo, path, line_number, text, run_id) VALUES (?, ?, ?, ?, ?) ON CONFLICT (repo, path, line_number) DO UPDATE SET text = excluded.text, run_id = excluded.run_id"
+)
+
+// runValues returns the query, the search as JSON and the start time that
+// identify a run in the runs table.
+func runValues(args *Arguments) (query, search, started string, err error) {
+	data, err := json.Marshal(queryOf(args))
+	if err != nil {
+		return "", "", "", err
+	}
+	start := args.Started
+	if start.IsZero() {
+		start = time.Now()
+	}
+	return strings.Join(args.queries(), ","), string(data), start.UTC().Format(time.RFC3339), nil
+}
+
+// sqlString quotes s as an SQL string literal.
+func sqlString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// bindSQL replaces the ? placeholders of stmt with literals, in order.
+func bindSQL(stmt string, literals ...string) string {
+	var b strings.Builder
+	for _, literal := range literals {
+		i := strings.IndexByte(stmt, '?')
+		b.WriteString(stmt[:i])
+		b.WriteString(literal)
+		stmt = stmt[i+1:]
+	}
+	b.WriteString(stmt)
+	return b.String()
+}
+
+// writeSQL writes hits as an SQL script that records the run and upserts
+// its repositories, files and matched lines into an SQLite database, eg.
+// with sqlite3 findings.db < script.sql.
+func writeSQL(w io.Writer, hits *grepapp.Results, args *Arguments) error {
+	query, search, started, err := runValues(args)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	b.WriteString(sqliteSchema)
+	b.WriteString("BEGIN;\n")
+	b.WriteString(bindSQL(insertRun, sqlString(query), sqlString(search), sqlString(started)) + ";\n")
+	const run = "(SELECT max(id) FROM runs)"
+	for _, hit := range hits.Hits {
+		stars := "NULL"
+		if hit.Stars != nil {
+			stars = strconv.Itoa(*hit.Stars)
+		}
+		b.WriteString(bindSQL(upsertRepo, sqlString(hit.Repo), stars) + ";\n")
+		b.WriteString(bindSQL(upsertFile, sqlString(hit.Repo), sqlString(hit.Path), sqlString(hit.Lang), run) + ";\n")
+		for _, line := range hit.Lines {
+			if line.Context {
+				continue
+			}
+			b.WriteString(bindSQL(upsertMatch, sqlString(hit.Repo), sqlString(hit.Path), strconv.Itoa(line.LineNumber), sqlString(stripANSI(line.Text)), run) + ";\n")
+		}
+	}
+	b.WriteString("COMMIT;\n")
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// writeSQLite stores hits in the database at path, creating it if needed,
+// in a single transaction. The driver is pure Go, which keeps the build free
+// of cgo.
+func writeSQLite(ctx context.Context, path string, hits *grepapp.Results, args *Arguments) error {
+	query, search, started, err := runValues(args)
+	if err != nil {
+		return err
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return fmt.Errorf("Cannot open %s: %w", path, err)
+	}
+	defer db.Close()
+	if err := storeHits(ctx, db, hits, query, search, started); err != nil {
+		return fmt.Errorf("Cannot write to %s: %w", path, err)
+	}
+	return db.Close()
+}
+
+// storeHits records a run in db and upserts hits through prepared
+// statements, rolling everything back on error.
+func storeHits(ctx context.Context, db *sql.DB, hits *grepapp.Results, query, search, started string) error {
+	if _, err := db.ExecContext(ctx, sqliteSchema); err != nil {
+		return err
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// Does nothing once committed
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, insertRun, query, search, started)
+	if err != nil {
+		return err
+	}
+	run, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	repo, err := tx.PrepareContext(ctx, upsertRepo)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+	file, err := tx.PrepareContext(ctx, upsertFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	match, err := tx.PrepareContext(ctx, upsertMatch)
+	if err != nil {
+		return err
+	}
+	defer match.Close()
+	for _, hit := range hits.Hits {
+		if _, err := repo.ExecContext(ctx, hit.Repo, hit.Stars); err != nil {
+			return err
+		}
+		if _, err := file.ExecContext(ctx, hit.Repo, hit.Path, hit.Lang, run); err != nil {
+			return err
+		}
+		for _, line := range hit.Lines {
+			if line.Context {
+				continue
+			}
+			if _, err := match.ExecContext(ctx, hit.Repo, hit.Path, line.LineNumber, stripANSI(line.Text), run); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,57 @@
+package main_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	grepgithub "github.com/aviadhahami/grepgithub-go"
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteSQL(t *testing.T) {
+	hits := &grepapp.Results{}
+	hits.AddHit("example/repo", "main.go", 3, "it's "+grepapp.C_MARK+"foo"+grepapp.C_RST)
+	args := &grepgithub.Arguments{Started: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	args.Query = "foo"
+
+	var buf bytes.Buffer
+	assert.NoError(t, grepgithub.WriteSQL(&buf, hits, args))
+	assert.Contains(t, buf.String(), `INSERT INTO runs (query, search, started_at) VALUES ('foo', '{"query":"foo","case_sensitive":false,"regex":false,"whole_words":false}', '2024-05-01T12:00:00Z');`)
+	assert.Contains(t, buf.String(), `VALUES ('example/repo', 'main.go', 3, 'it''s foo', (SELECT max(id) FROM runs))`)
+	assert.True(t, strings.HasSuffix(buf.String(), "COMMIT;\n"))
+}
+
+func TestWriteSQLiteUpserts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "findings.db")
+	args := &grepgithub.Arguments{}
+	args.Query = "foo"
+
+	hits := &grepapp.Results{}
+	hits.AddHit("example/repo", "main.go", 3, "foo")
+	hits.AddHit("example/repo", "util.go", 8, "foo")
+	assert.NoError(t, grepgithub.WriteSQLite(context.Background(), path, hits, args))
+	hits.AddHit("example/repo", "main.go", 9, "it's foo()")
+	stars := 42
+	hits.Hits[0].Stars = &stars
+	assert.NoError(t, grepgithub.WriteSQLite(context.Background(), path, hits, args))
+
+	db, err := sql.Open("sqlite", path)
+	assert.NoError(t, err)
+	defer db.Close()
+	var runs, repos, files, matches, runID int
+	row := db.QueryRow("SELECT (SELECT count(*) FROM runs), (SELECT count(*) FROM repos), (SELECT count(*) FROM files), (SELECT count(*) FROM matches), (SELECT min(run_id) FROM files)")
+	assert.NoError(t, row.Scan(&runs, &repos, &files, &matches, &runID))
+	assert.Equal(t, []int{2, 1, 2, 3, 2}, []int{runs, repos, files, matches, runID})
+
+	var text string
+	row = db.QueryRow("SELECT text, (SELECT stars FROM repos) FROM matches WHERE line_number = 9")
+	assert.NoError(t, row.Scan(&text, &stars))
+	assert.Equal(t, "it's foo()", text)
+	assert.Equal(t, 42, stars)
+}
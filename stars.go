@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+)
+
+// stars looks up the GitHub star count of the repositories -with-stars
+// annotates hits with, asking for each repository once. Once GitHub rate
+// limits the lookups, the remaining repositories are left without stars.
+type stars struct {
+	client *grepapp.Client
+	token  string
+	warn   io.Writer
+	// counts holds the repositories looked up so far, nil for those whose
+	// stars are not known
+	counts  map[string]*int
+	limited bool
+}
+
+func newStars(client *grepapp.Client, token string, warn io.Writer) *stars {
+	return &stars{client: client, token: token, warn: warn, counts: make(map[string]*int)}
+}
+
+// annotate sets the stars of every hit in a GitHub repository. Only a
+// canceled ctx is an error, other failures leave stars unknown.
+func (s *stars) annotate(ctx context.Context, hits *grepapp.Results) error {
+	for i := range hits.Hits {
+		hit := &hits.Hits[i]
+		count, ok := s.counts[hit.Repo]
+		if !ok {
+			var err error
+			count, err = s.lookup(ctx, hit.Repo)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil {
+				fmt.Fprintf(s.warn, "Warning: cannot look up the stars of %s: %s\n", hit.Repo, err)
+			}
+			s.counts[hit.Repo] = count
+		}
+		hit.Stars = count
+	}
+	return nil
+}
+
+// lookup asks GitHub for the stars of repo, returning nil when repo is not
+// on GitHub or lookups are rate limited.
+func (s *stars) lookup(ctx context.Context, repo string) (*int, error) {
+	link := grepapp.RepoAPIURL(repo)
+	if link == "" || s.limited {
+		return nil, nil
+	}
+	// Stars are not worth holding up the search for
+	req, err := http.NewRequestWithContext(skipGithubWait(ctx), http.MethodGet, link, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if s.client.UserAgent != "" {
+		req.Header.Set("User-Agent", s.client.UserAgent)
+	}
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	resp, err := s.client.HTTPClient.Do(req)
+	if errors.Is(err, errGithubRateLimited) {
+		s.limit()
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode == http.StatusForbidden && resp.Header.Get("X-RateLimit-Remaining") == "0":
+		s.limit()
+		return nil, nil
+	case resp.StatusCode == http.StatusNotFound:
+		// Deleted and private repositories stay in grep.app's index
+		return nil, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var data struct {
+		Stars int `json:"stargazers_count"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, err
+	}
+	return &data.Stars, nil
+}
+
+// limit stops further lookups once GitHub, or the limiter in front of it,
+// rate limits them.
+func (s *stars) limit() {
+	s.limited = true
+	hint := ""
+	if s.token == "" {
+		hint = ", use -github-token for a higher limit"
+	}
+	fmt.Fprintf(s.warn, "Warning: GitHub rate limits star lookups, the remaining repositories are left without stars%s\n", hint)
+}
@@ -0,0 +1,80 @@
+package main_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	grepgithub "github.com/aviadhahami/grepgithub-go"
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStarsAnnotate(t *testing.T) {
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		switch r.URL.Path {
+		case "/repos/example/popular":
+			w.Write([]byte(`{"full_name":"example/popular","stargazers_count":1200}`))
+		case "/repos/example/limited":
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	client := grepapp.NewClient()
+	client.HTTPClient = &http.Client{Transport: redirect{server}}
+
+	hits := &grepapp.Results{}
+	hits.AddHit("example/popular", "main.go", 1, "foo")
+	hits.AddHit("example/deleted", "main.go", 1, "foo")
+	hits.AddHit("gitlab.com/example/repo", "main.go", 1, "foo")
+	hits.AddHit("example/popular", "README.md", 1, "foo")
+	hits.AddHit("example/limited", "main.go", 1, "foo")
+	hits.AddHit("example/other", "main.go", 1, "foo")
+
+	var warn bytes.Buffer
+	assert.NoError(t, grepgithub.NewStars(client, "secret", &warn).Annotate(context.Background(), hits))
+	assert.Equal(t, []string{"/repos/example/popular", "/repos/example/deleted", "/repos/example/limited"}, requested)
+	var stars []any
+	for _, hit := range hits.Hits {
+		if hit.Stars == nil {
+			stars = append(stars, nil)
+		} else {
+			stars = append(stars, *hit.Stars)
+		}
+	}
+	assert.Equal(t, []any{1200, nil, nil, 1200, nil, nil}, stars)
+	assert.Equal(t, "Warning: GitHub rate limits star lookups, the remaining repositories are left without stars\n", warn.String())
+}
+
+func TestStarsAnnotateDoesNotWaitForLimiter(t *testing.T) {
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		w.Write([]byte(`{"stargazers_count":7}`))
+	}))
+	defer server.Close()
+	var warn bytes.Buffer
+	client := grepapp.NewClient()
+	// A burst of one and a request an hour, so the second lookup would wait
+	client.HTTPClient = &http.Client{Transport: grepgithub.NewGithubLimiter(redirect{server}, 1, 1, &warn)}
+
+	hits := &grepapp.Results{}
+	hits.AddHit("example/a", "main.go", 1, "foo")
+	hits.AddHit("example/b", "main.go", 1, "foo")
+	hits.AddHit("example/c", "main.go", 1, "foo")
+
+	assert.NoError(t, grepgithub.NewStars(client, "", &warn).Annotate(context.Background(), hits))
+	assert.Equal(t, []string{"/repos/example/a"}, requested)
+	assert.Equal(t, 7, *hits.Hits[0].Stars)
+	assert.Nil(t, hits.Hits[1].Stars)
+	assert.Nil(t, hits.Hits[2].Stars)
+	assert.Equal(t, "Warning: GitHub rate limits star lookups, the remaining repositories are left without stars, use -github-token for a higher limit\n", warn.String())
+}
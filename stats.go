@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+)
+
+// matchedLines returns the number of matched lines of hits, leaving out
+// context lines.
+func matchedLines(hits *grepapp.Results) int {
+	lines := 0
+	for i := range hits.Hits {
+		lines += hits.Hits[i].MatchedLines()
+	}
+	return lines
+}
+
+// writeStats writes a one line summary of the scan, meant for stderr so that
+// it does not mix with the results.
+func writeStats(w io.Writer, hits *grepapp.Results, elapsed time.Duration) error {
+	repos := map[string]bool{}
+	for _, hit := range hits.Hits {
+		repos[hit.Repo] = true
+	}
+	_, err := fmt.Fprintf(w, "%d files, %d lines in %d repositories (%s)\n", len(hits.Hits), matchedLines(hits), len(repos), elapsed.Round(time.Millisecond))
+	return err
+}
@@ -0,0 +1,23 @@
+package main_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	grepgithub "github.com/aviadhahami/grepgithub-go"
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteStats(t *testing.T) {
+	hits := &grepapp.Results{}
+	hits.AddHit("example/repo", "main.go", 3, "foo")
+	hits.AddHit("example/repo", "main.go", 12, "foo")
+	hits.AddHit("example/repo", "README.md", 7, "foo")
+	hits.AddHit("other/repo", "foo.py", 1, "foo")
+
+	var buf bytes.Buffer
+	assert.NoError(t, grepgithub.WriteStats(&buf, hits, 1234567*time.Microsecond))
+	assert.Equal(t, "3 files, 4 lines in 2 repositories (1.235s)\n", buf.String())
+}
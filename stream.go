@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+)
+
+// streamMemory is how many files and lines -stream remembers to drop
+// duplicates, bounding its memory use whatever the size of the scan.
+const streamMemory = 100_000
+
+// seenSet remembers up to size keys, forgetting the oldest first.
+type seenSet struct {
+	size  int
+	keys  map[string]bool
+	order []string
+	next  int
+}
+
+func newSeenSet(size int) *seenSet {
+	return &seenSet{size: size, keys: make(map[string]bool)}
+}
+
+func (s *seenSet) has(key string) bool {
+	return s.keys[key]
+}
+
+// add remembers key and reports whether it was new.
+func (s *seenSet) add(key string) bool {
+	if s.keys[key] {
+		return false
+	}
+	if len(s.order) < s.size {
+		s.order = append(s.order, key)
+	} else {
+		delete(s.keys, s.order[s.next])
+		s.order[s.next] = key
+		s.next = (s.next + 1) % s.size
+	}
+	s.keys[key] = true
+	return true
+}
+
+// dedup drops the lines of page that were already streamed and the files
+// left without any new line. Once files reaches max, files not streamed yet
+// are dropped as well, like limit does, and dedup reports that max was
+// reached. A max of 0 means no limit.
+func (s *seenSet) dedup(page *grepapp.Results, files *int, max int) bool {
+	kept := page.Hits[:0]
+	for _, hit := range page.Hits {
+		file := hit.Repo + "\x00" + hit.Path
+		newFile := !s.has(file)
+		if newFile && max > 0 && *files >= max {
+			continue
+		}
+		lines := hit.Lines[:0]
+		for _, line := range hit.Lines {
+			key := file + "\x00" + strconv.Itoa(line.LineNumber)
+			if line.Context {
+				key += "\x00context"
+			}
+			if s.add(key) {
+				lines = append(lines, line)
+			}
+		}
+		hit.Lines = lines
+		if !newFile && len(lines) == 0 {
+			continue
+		}
+		if newFile {
+			s.add(file)
+			*files++
+		}
+		kept = append(kept, hit)
+	}
+	page.Hits = kept
+	return max > 0 && *files >= max
+}
@@ -0,0 +1,42 @@
+package main_test
+
+import (
+	"testing"
+
+	grepgithub "github.com/aviadhahami/grepgithub-go"
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeenSetDedup(t *testing.T) {
+	seen := grepgithub.NewSeenSet(100)
+	files := 0
+
+	page1 := &grepapp.Results{}
+	page1.AddHit("example/repo", "a.go", 1, "a")
+	page1.AddHit("example/repo", "b.go", 1, "b")
+	assert.False(t, seen.Dedup(page1, &files, 0))
+	assert.Equal(t, 2, files)
+	assert.Len(t, page1.Hits, 2)
+
+	page2 := &grepapp.Results{}
+	page2.AddHit("example/repo", "a.go", 1, "a")
+	page2.AddHit("example/repo", "b.go", 1, "b")
+	page2.AddHit("example/repo", "b.go", 5, "b again")
+	page2.AddHit("example/repo", "c.go", 1, "c")
+	page2.AddHit("example/repo", "d.go", 1, "d")
+	assert.True(t, seen.Dedup(page2, &files, 3))
+	assert.Equal(t, 3, files)
+	assert.Equal(t, []string{"example/repo/b.go", "example/repo/c.go"}, paths(page2))
+	assert.Equal(t, []grepapp.Line{{LineNumber: 5, Text: "b again"}}, page2.Hits[0].Lines)
+}
+
+func TestSeenSetForgetsOldestKeys(t *testing.T) {
+	seen := grepgithub.NewSeenSet(2)
+	assert.True(t, seen.Add("a"))
+	assert.True(t, seen.Add("b"))
+	assert.False(t, seen.Add("a"))
+	assert.True(t, seen.Add("c"))
+	assert.True(t, seen.Add("a"))
+	assert.False(t, seen.Add("c"))
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"text/template"
+
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+)
+
+// writeTemplate executes the -template once per matched file, with the file
+// as its data. A newline is added after each file unless the template
+// output already ends with one.
+func writeTemplate(w io.Writer, hits *grepapp.Results, tmpl *template.Template) error {
+	var buf bytes.Buffer
+	for i := range hits.Hits {
+		buf.Reset()
+		if err := tmpl.Execute(&buf, &hits.Hits[i]); err != nil {
+			return err
+		}
+		if !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+			buf.WriteByte('\n')
+		}
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package main_test
+
+import (
+	"bytes"
+	"testing"
+
+	grepgithub "github.com/aviadhahami/grepgithub-go"
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	hits := &grepapp.Results{}
+	hits.AddHit("example/repo", "main.go", 3, "foo")
+	hits.AddHit("example/repo", "main.go", 8, "bar")
+	hits.AddHit("other/repo", "lib.py", 1, "baz")
+
+	args, err := grepgithub.ParseArguments([]string{"-q", "foo", "-template", "{{.Repo}}:{{.Path}}"})
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	assert.NoError(t, grepgithub.Render(&buf, hits, args))
+	assert.Equal(t, "example/repo:main.go\nother/repo:lib.py\n", buf.String())
+
+	args, err = grepgithub.ParseArguments([]string{"-q", "foo", "-template", "{{range .Lines}}{{.LineNumber}} {{.Text}}\n{{end}}"})
+	assert.NoError(t, err)
+	buf.Reset()
+	assert.NoError(t, grepgithub.Render(&buf, hits, args))
+	assert.Equal(t, "3 foo\n8 bar\n1 baz\n", buf.String())
+}
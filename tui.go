@@ -0,0 +1,343 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+	"golang.org/x/term"
+)
+
+// browser is the state of the -tui result browser: a scrollable list of
+// files above a preview of the selected file's lines.
+type browser struct {
+	hits      []grepapp.Result
+	visible   []int // indexes of the hits matching filter
+	selected  int   // index into visible
+	offset    int   // first row of visible shown in the list
+	rows      int   // height of the list when last drawn
+	filter    string
+	filtering bool
+	status    string
+	copy      func(text string) error
+}
+
+func newBrowser(hits []grepapp.Result, copy func(string) error) *browser {
+	b := &browser{hits: hits, rows: 1, copy: copy}
+	b.applyFilter()
+	return b
+}
+
+// applyFilter keeps the files whose repo/path contains the filter, ignoring
+// case, and selects the first of them.
+func (b *browser) applyFilter() {
+	filter := strings.ToLower(b.filter)
+	b.visible = b.visible[:0]
+	for i, hit := range b.hits {
+		if strings.Contains(strings.ToLower(hit.Repo+"/"+hit.Path), filter) {
+			b.visible = append(b.visible, i)
+		}
+	}
+	b.selected, b.offset = 0, 0
+}
+
+// current returns the selected file, or nil if the filter matches none.
+func (b *browser) current() *grepapp.Result {
+	if len(b.visible) == 0 {
+		return nil
+	}
+	return &b.hits[b.visible[b.selected]]
+}
+
+func (b *browser) move(delta int) {
+	b.selected = max(min(b.selected+delta, len(b.visible)-1), 0)
+}
+
+// handle applies a key read by readKey and reports whether the browser
+// should close.
+func (b *browser) handle(key string) bool {
+	b.status = ""
+	switch key {
+	case "ctrl-c":
+		return true
+	case "up":
+		b.move(-1)
+		return false
+	case "down":
+		b.move(1)
+		return false
+	case "pgup":
+		b.move(-b.rows)
+		return false
+	case "pgdown":
+		b.move(b.rows)
+		return false
+	}
+
+	if b.filtering {
+		switch key {
+		case "enter":
+			b.filtering = false
+		case "esc":
+			b.filter, b.filtering = "", false
+			b.applyFilter()
+		case "backspace":
+			if b.filter != "" {
+				_, size := utf8.DecodeLastRuneInString(b.filter)
+				b.filter = b.filter[:len(b.filter)-size]
+				b.applyFilter()
+			}
+		default:
+			if utf8.RuneCountInString(key) == 1 {
+				b.filter += key
+				b.applyFilter()
+			}
+		}
+		return false
+	}
+
+	switch key {
+	case "q", "esc":
+		return true
+	case "k":
+		b.move(-1)
+	case "j":
+		b.move(1)
+	case "home", "g":
+		b.move(-len(b.visible))
+	case "end", "G":
+		b.move(len(b.visible))
+	case "/":
+		b.filtering = true
+	case "enter":
+		b.copyURL()
+	}
+	return false
+}
+
+// copyURL copies the GitHub permalink of the selected file's first matched
+// line.
+func (b *browser) copyURL() {
+	hit := b.current()
+	if hit == nil {
+		return
+	}
+	link, err := clipText(hit, "url")
+	if err != nil {
+		b.status = err.Error()
+		return
+	}
+	if err := b.copy(link); err != nil {
+		b.status = fmt.Sprintf("Cannot copy %s: %s", link, err)
+		return
+	}
+	b.status = "Copied " + link
+}
+
+// draw writes a whole frame of width by height cells. Matches are colored
+// with color, or left plain when it is empty.
+func (b *browser) draw(w io.Writer, width, height int, color string) {
+	b.rows = max((height-2)/2, 1)
+	if b.selected < b.offset {
+		b.offset = b.selected
+	}
+	if b.selected >= b.offset+b.rows {
+		b.offset = b.selected - b.rows + 1
+	}
+
+	var lines []string
+	for row := 0; row < b.rows; row++ {
+		i := b.offset + row
+		if i >= len(b.visible) {
+			lines = append(lines, "")
+			continue
+		}
+		hit := b.hits[b.visible[i]]
+		line := fmt.Sprintf("  %s:%s (%d)", hit.Repo, hit.Path, hit.MatchedLines())
+		if i == b.selected {
+			line = ">" + line[1:]
+			if color != "" {
+				line = "\033[7m" + truncate(line, width) + grepapp.C_RST
+			}
+		}
+		lines = append(lines, line)
+	}
+
+	hit := b.current()
+	title := "--"
+	if hit != nil {
+		title = fmt.Sprintf("-- %s/%s ", hit.Repo, hit.Path)
+	}
+	lines = append(lines, title+strings.Repeat("-", max(width-utf8.RuneCountInString(title), 0)))
+	for row := 0; row < height-2-b.rows; row++ {
+		if hit == nil || row >= len(hit.Lines) {
+			lines = append(lines, "")
+			continue
+		}
+		line := hit.Lines[row]
+		text := stripANSI(line.Text)
+		if color != "" {
+			text = strings.ReplaceAll(line.Text, grepapp.C_MARK, color)
+		}
+		lines = append(lines, fmt.Sprintf("%d%s %s", line.LineNumber, separator(line), text))
+	}
+
+	switch {
+	case b.filtering:
+		lines = append(lines, "/"+b.filter)
+	case b.status != "":
+		lines = append(lines, b.status)
+	default:
+		lines = append(lines, fmt.Sprintf("%d of %d files  up/down move  / filter  enter copy URL  q quit", min(b.selected+1, len(b.visible)), len(b.visible)))
+	}
+
+	io.WriteString(w, "\033[H\033[2J")
+	for i, line := range lines {
+		if i > 0 {
+			io.WriteString(w, "\r\n")
+		}
+		io.WriteString(w, truncate(line, width))
+	}
+}
+
+// truncate cuts s to width visible runes, keeping its escape sequences and
+// resetting colors if any were cut.
+func truncate(s string, width int) string {
+	var out strings.Builder
+	visible := 0
+	for i := 0; i < len(s); {
+		if loc := ansiRe.FindStringIndex(s[i:]); loc != nil && loc[0] == 0 {
+			out.WriteString(s[i : i+loc[1]])
+			i += loc[1]
+			continue
+		}
+		if visible == width {
+			if strings.Contains(s, "\033") {
+				out.WriteString(grepapp.C_RST)
+			}
+			break
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == '\t' {
+			r = ' '
+		}
+		out.WriteRune(r)
+		visible++
+		i += size
+	}
+	return out.String()
+}
+
+// keys names the escape sequences of the keys the browser uses.
+var keys = map[string]string{
+	"[A": "up", "OA": "up",
+	"[B": "down", "OB": "down",
+	"[5~": "pgup",
+	"[6~": "pgdown",
+	"[H":  "home", "OH": "home", "[1~": "home",
+	"[F": "end", "OF": "end", "[4~": "end",
+}
+
+// readKey reads a key press from a terminal in raw mode. Special keys are
+// named, such as up, enter or ctrl-c, others are returned as typed and
+// unknown sequences as an empty string.
+func readKey(r *bufio.Reader) (string, error) {
+	c, _, err := r.ReadRune()
+	if err != nil {
+		return "", err
+	}
+	switch c {
+	case 3:
+		return "ctrl-c", nil
+	case '\r', '\n':
+		return "enter", nil
+	case 127, '\b':
+		return "backspace", nil
+	case 27:
+	default:
+		if c < ' ' {
+			return "", nil
+		}
+		return string(c), nil
+	}
+
+	// A lone escape is the Esc key, otherwise it starts a sequence ending
+	// with a letter or ~
+	if r.Buffered() == 0 {
+		return "esc", nil
+	}
+	var seq []byte
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		seq = append(seq, c)
+		if len(seq) > 1 && (c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c == '~') {
+			return keys[string(seq)], nil
+		}
+	}
+}
+
+// osc52 copies text to the clipboard through the terminal, which works over
+// SSH too but not in every terminal.
+func osc52(w io.Writer) func(string) error {
+	return func(text string) error {
+		_, err := fmt.Fprintf(w, "\033]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(text)))
+		return err
+	}
+}
+
+// browse shows hits in the -tui browser until it is closed.
+func browse(hits *grepapp.Results, args *Arguments) error {
+	in, out := int(os.Stdin.Fd()), int(os.Stdout.Fd())
+	if !term.IsTerminal(in) || !term.IsTerminal(out) {
+		return errors.New("-tui requires a terminal")
+	}
+	state, err := term.MakeRaw(in)
+	if err != nil {
+		return fmt.Errorf("Cannot start the browser: %w", err)
+	}
+	defer term.Restore(in, state)
+	// Use the alternate screen so the shell is left as it was
+	fmt.Fprint(os.Stdout, "\033[?1049h\033[?25l")
+	defer fmt.Fprint(os.Stdout, "\033[?25h\033[?1049l")
+
+	color := args.MatchColor
+	if args.Monochrome {
+		color = ""
+	}
+	// Terminals that support it can copy without a clipboard tool
+	b := newBrowser(hits.Hits, func(text string) error {
+		if err := copyToClipboard(text); !errors.Is(err, errNoClipboard) {
+			return err
+		}
+		return osc52(os.Stdout)(text)
+	})
+	input := bufio.NewReader(os.Stdin)
+	for {
+		width, height, err := term.GetSize(out)
+		if err != nil || width <= 0 || height <= 0 {
+			width, height = 80, 24
+		}
+		var frame bytes.Buffer
+		b.draw(&frame, width, height, color)
+		if _, err := os.Stdout.Write(frame.Bytes()); err != nil {
+			return err
+		}
+		key, err := readKey(input)
+		if err != nil {
+			return err
+		}
+		if b.handle(key) {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package main_test
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	grepgithub "github.com/aviadhahami/grepgithub-go"
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+	"github.com/stretchr/testify/assert"
+)
+
+func browserHits() []grepapp.Result {
+	hits := &grepapp.Results{}
+	hits.AddHit("example/repo", "main.go", 3, "foo("+grepapp.C_MARK+"x"+grepapp.C_RST+")")
+	hits.AddHit("example/repo", "README.md", 7, "bar")
+	hits.AddHit("git.example.com/repo", "main.go", 1, "baz")
+	hits.Merge(&grepapp.Results{Hits: []grepapp.Result{{Repo: "example/repo", Path: "main.go", Lines: []grepapp.Line{{LineNumber: 4, Text: "context", Context: true}}}}})
+	return hits.Hits
+}
+
+func TestBrowserNavigation(t *testing.T) {
+	b := grepgithub.NewBrowser(browserHits(), nil)
+	assert.Equal(t, "main.go", b.Current().Path)
+
+	b.Handle("down")
+	b.Handle("j")
+	b.Handle("down")
+	assert.Equal(t, "git.example.com/repo", b.Current().Repo)
+	b.Handle("home")
+	assert.Equal(t, "example/repo", b.Current().Repo)
+	b.Handle("end")
+	assert.Equal(t, "git.example.com/repo", b.Current().Repo)
+
+	assert.False(t, b.Handle("x"))
+	assert.True(t, b.Handle("q"))
+}
+
+func TestBrowserFilter(t *testing.T) {
+	b := grepgithub.NewBrowser(browserHits(), nil)
+	for _, key := range []string{"/", "R", "e", "a", "d", "x", "backspace"} {
+		assert.False(t, b.Handle(key), key)
+	}
+	assert.Equal(t, "README.md", b.Current().Path)
+	b.Handle("down")
+	assert.Equal(t, "README.md", b.Current().Path)
+
+	// q is part of the filter until it is closed
+	b.Handle("q")
+	assert.Nil(t, b.Current())
+	b.Handle("esc")
+	assert.Equal(t, "main.go", b.Current().Path)
+	assert.True(t, b.Handle("q"))
+}
+
+func TestBrowserCopyURL(t *testing.T) {
+	var copied []string
+	b := grepgithub.NewBrowser(browserHits(), func(text string) error {
+		copied = append(copied, text)
+		return nil
+	})
+	b.Handle("enter")
+	b.Handle("end")
+	b.Handle("enter")
+	assert.Equal(t, []string{"https://github.com/example/repo/blob/HEAD/main.go#L3"}, copied)
+
+	var frame bytes.Buffer
+	b.Draw(&frame, 80, 6, "")
+	assert.Contains(t, frame.String(), "git.example.com/repo is not a GitHub repository")
+}
+
+func TestBrowserDraw(t *testing.T) {
+	b := grepgithub.NewBrowser(browserHits(), nil)
+	b.Handle("down")
+
+	var frame bytes.Buffer
+	b.Draw(&frame, 30, 8, "")
+	lines := strings.Split(strings.TrimPrefix(frame.String(), "\x1b[H\x1b[2J"), "\r\n")
+	assert.Equal(t, []string{
+		"  example/repo:main.go (1)",
+		"> example/repo:README.md (1)",
+		"  git.example.com/repo:main.go",
+		"-- example/repo/README.md ----",
+		"7: bar",
+		"",
+		"",
+		"2 of 3 files  up/down move  / ",
+	}, lines)
+	assert.NotContains(t, frame.String(), "\x1b[7m")
+
+	frame.Reset()
+	b.Handle("up")
+	b.Draw(&frame, 80, 8, "\x1b[36m")
+	assert.Contains(t, frame.String(), "\x1b[7m> example/repo:main.go (1)")
+	assert.Contains(t, frame.String(), "3: foo(\x1b[36mx"+grepapp.C_RST+")")
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "héllo", grepgithub.Truncate("héllo", 10))
+	assert.Equal(t, "hé", grepgithub.Truncate("héllo", 2))
+	assert.Equal(t, "a\x1b[32mb"+grepapp.C_RST, grepgithub.Truncate("a\x1b[32mbc\x1b[0m", 2))
+}
+
+func TestReadKey(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("q\x1b[A\x1b[6~\x1bOB\r\x7f\x03é\x1b[99z"))
+	var keys []string
+	for range 9 {
+		key, err := grepgithub.ReadKey(r)
+		assert.NoError(t, err)
+		keys = append(keys, key)
+	}
+	assert.Equal(t, []string{"q", "up", "pgdown", "down", "enter", "backspace", "ctrl-c", "é", ""}, keys)
+
+	r = bufio.NewReader(strings.NewReader("\x1b"))
+	key, err := grepgithub.ReadKey(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "esc", key)
+}
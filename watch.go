@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+)
+
+// watch runs the search every args.Watch until ctx is done, printing the
+// files and lines that no earlier cycle found. The first cycle prints
+// everything, unless -diff gave a previous output to compare with. With
+// -exit-on-new, watch returns once a later cycle finds something. Failed
+// cycles are reported to warn and retried on the next one. The client is
+// shared by every cycle, so an adaptive delay carries over. New findings
+// after the first cycle are also posted to -webhook.
+func watch(ctx context.Context, client *grepapp.Client, w *bufio.Writer, args *Arguments, warn io.Writer) error {
+	known := &grepapp.Results{Hits: []grepapp.Result{}}
+	if args.Diff != nil {
+		known.Merge(args.Diff)
+	}
+	for cycle := 1; ; cycle++ {
+		hits, _, err := search(ctx, client, args, nil, func(*grepapp.Results) error { return nil })
+		var partial *partialError
+		switch {
+		case ctx.Err() != nil:
+			return nil
+		case errors.As(err, &partial):
+			fmt.Fprintf(warn, "Warning: %s\n", errorMessage(err))
+		case err != nil:
+			fmt.Fprintf(warn, "Warning: %s, trying again in %s\n", errorMessage(err), args.Watch)
+			hits = nil
+		}
+
+		if hits != nil {
+			if args.FirstLineOnly {
+				firstLineOnly(hits)
+			}
+			if args.MinMatches > 0 {
+				minMatches(hits, args.MinMatches)
+			}
+			if args.MaxFilesPerRepo > 0 {
+				maxFilesPerRepo(hits, args.MaxFilesPerRepo)
+			}
+			baseline := cycle == 1 && args.Diff == nil
+			added := hits
+			if !baseline {
+				added, _ = diffHits(known, hits)
+			}
+			known.Merge(hits)
+			sortHits(added, args.Sort)
+			if args.Webhook != "" && !baseline && len(added.Hits) > 0 {
+				payload := newWebhookPayload(added, args, time.Now())
+				if err := postWebhook(ctx, client, args, payload); err != nil && ctx.Err() == nil {
+					fmt.Fprintf(warn, "Warning: webhook failed: %s\n", err)
+				}
+			}
+			if err := render(w, added, args); err != nil {
+				return err
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			if args.ExitOnNew && !baseline && len(added.Hits) > 0 {
+				return nil
+			}
+		}
+
+		timer := time.NewTimer(args.Watch)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
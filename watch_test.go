@@ -0,0 +1,95 @@
+package main_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	grepgithub "github.com/aviadhahami/grepgithub-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunWatch(t *testing.T) {
+	cycles := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cycles++
+		switch cycles {
+		case 1:
+			_, _ = w.Write([]byte(`{"facets":{"count":1},"hits":{"hits":[{"repo":{"raw":"example/repo"},"path":{"raw":"main.go"}}]}}`))
+		case 2:
+			w.WriteHeader(http.StatusInternalServerError)
+		case 3:
+			// grep.app dropping a file is not worth reporting
+			_, _ = w.Write([]byte(`{"facets":{"count":0},"hits":{"hits":[]}}`))
+		default:
+			_, _ = w.Write([]byte(`{"facets":{"count":2},"hits":{"hits":[` +
+				`{"repo":{"raw":"example/repo"},"path":{"raw":"main.go"}},` +
+				`{"repo":{"raw":"example/repo"},"path":{"raw":"new.go"}}]}}`))
+		}
+	}))
+	defer server.Close()
+
+	args, err := grepgithub.ParseArguments([]string{"-q", "foo", "-delay", "0", "-retries", "0", "-quiet", "-jsonl", "-base-url", server.URL, "-watch", "10ms", "-exit-on-new"})
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	assert.NoError(t, grepgithub.Run(context.Background(), args, &buf))
+	assert.Equal(t, 4, cycles)
+	assert.Equal(t, `{"repo":"example/repo","path":"main.go","lang":"Go","lines":[]}`+"\n"+
+		`{"repo":"example/repo","path":"new.go","lang":"Go","lines":[]}`+"\n", buf.String())
+}
+
+func TestRunWatchStopsWhenCanceled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"facets":{"count":0},"hits":{"hits":[]}}`))
+	}))
+	defer server.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	args, err := grepgithub.ParseArguments([]string{"-q", "foo", "-delay", "0", "-base-url", server.URL, "-watch", "1h"})
+	assert.NoError(t, err)
+	assert.NoError(t, grepgithub.Run(ctx, args, &bytes.Buffer{}))
+}
+
+func TestRunWatchWebhook(t *testing.T) {
+	cycles := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cycles++
+		path := "main.go"
+		if cycles > 1 {
+			path = "new.go"
+		}
+		_, _ = w.Write([]byte(`{"facets":{"count":1},"hits":{"hits":[{"repo":{"raw":"example/repo"},"path":{"raw":"` + path + `"},"content":{"snippet":"<table><tr><td><div class=\"lineno\">3</div></td><td><pre>x := <mark>foo</mark>()</pre></td></tr></table>"}}]}}`))
+	}))
+	defer server.Close()
+	var posts []string
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		posts = append(posts, string(body))
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write(body)
+		assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), r.Header.Get("X-Grepgithub-Signature"))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		if len(posts) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer hook.Close()
+
+	args, err := grepgithub.ParseArguments([]string{"-q", "foo", "-delay", "0", "-retry-base", "1ms", "-m", "-base-url", server.URL,
+		"-watch", "10ms", "-exit-on-new", "-webhook", hook.URL, "-webhook-secret", "secret"})
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	assert.NoError(t, grepgithub.Run(context.Background(), args, &buf))
+	assert.Len(t, posts, 2)
+	assert.Equal(t, posts[0], posts[1])
+	assert.Regexp(t, `^\{"schema_version":1,"text":"1 new lines in 1 files matching \\"foo\\"","content":"1 new lines in 1 files matching \\"foo\\"",`+
+		`"query":\{"query":"foo","case_sensitive":false,"regex":false,"whole_words":false,"timestamp":"[^"]+"\},`+
+		`"hits":\[\{"repo":"example/repo","path":"new.go","lang":"Go","lines":\[\{"line_number":3,"text":"x := foo\(\)","matches":\[\{"start":5,"end":8\}\]\}\]\}\]\}$`, posts[1])
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+)
+
+// webhookPayload is the JSON body -webhook posts. Text and Content summarize
+// it for Slack and Discord incoming webhooks, which only show those fields.
+// Like -json output, its shape only changes along with SchemaVersion.
+type webhookPayload struct {
+	SchemaVersion int              `json:"schema_version"`
+	Text          string           `json:"text"`
+	Content       string           `json:"content"`
+	Query         jsonQuery        `json:"query"`
+	Hits          []grepapp.Result `json:"hits"`
+}
+
+// signatureHeader carries the HMAC-SHA256 of the body, keyed with
+// -webhook-secret, as sha256=<hex>.
+const signatureHeader = "X-Grepgithub-Signature"
+
+// newWebhookPayload describes hits, newly found by a -watch run at now,
+// without highlighting.
+func newWebhookPayload(hits *grepapp.Results, args *Arguments, now time.Time) webhookPayload {
+	plain := make([]grepapp.Result, len(hits.Hits))
+	lines := 0
+	for i, hit := range hits.Hits {
+		hit.Lines = slices.Clone(hit.Lines)
+		for j := range hit.Lines {
+			hit.Lines[j].Text = stripANSI(hit.Lines[j].Text)
+			if !hit.Lines[j].Context {
+				lines++
+			}
+		}
+		plain[i] = hit
+	}
+	query := queryOf(args)
+	query.Timestamp = now.UTC().Format(time.RFC3339)
+	text := fmt.Sprintf("%d new files matching %s", len(hits.Hits), joinQueries(args))
+	if lines > 0 {
+		text = fmt.Sprintf("%d new lines in %d files matching %s", lines, len(hits.Hits), joinQueries(args))
+	}
+	return webhookPayload{SchemaVersion: schemaVersion, Text: text, Content: text, Query: query, Hits: plain}
+}
+
+// joinQueries quotes the queries of args for a sentence.
+func joinQueries(args *Arguments) string {
+	quoted := make([]string, len(args.queries()))
+	for i, query := range args.queries() {
+		quoted[i] = strconv.Quote(query)
+	}
+	return strings.Join(quoted, " or ")
+}
+
+// sign returns the value of signatureHeader for body.
+func sign(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// postWebhook posts payload to args.Webhook, retrying failed requests, rate
+// limiting and server errors up to args.Retries times with exponential
+// backoff.
+func postWebhook(ctx context.Context, client *grepapp.Client, args *Arguments, payload webhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	for attempt := 0; ; attempt++ {
+		retry, err := postOnce(ctx, client, args, body)
+		if err == nil || !retry || attempt >= args.Retries || ctx.Err() != nil {
+			return err
+		}
+		timer := time.NewTimer(args.RetryBase << attempt)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// postOnce posts body to args.Webhook and reports whether a failure is
+// worth retrying.
+func postOnce(ctx context.Context, client *grepapp.Client, args *Arguments, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, args.Webhook, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if client.UserAgent != "" {
+		req.Header.Set("User-Agent", client.UserAgent)
+	}
+	if args.WebhookSecret != "" {
+		req.Header.Set(signatureHeader, sign(body, args.WebhookSecret))
+	}
+	resp, err := client.HTTPClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return retry, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return false, nil
+}
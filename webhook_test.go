@@ -0,0 +1,46 @@
+package main_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	grepgithub "github.com/aviadhahami/grepgithub-go"
+	"github.com/aviadhahami/grepgithub-go/pkg/grepapp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewWebhookPayload(t *testing.T) {
+	hits := &grepapp.Results{}
+	hits.AddHit("example/repo", "main.go", 3, grepapp.C_MARK+"foo"+grepapp.C_RST+"()")
+	hits.AddHit("example/repo", "main.go", 4, "bar()")
+	hits.Hits[0].Lines[1].Context = true
+	args := &grepgithub.Arguments{Any: []string{"foo", "bar"}}
+
+	payload := grepgithub.NewWebhookPayload(hits, args, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	assert.Equal(t, 1, payload.SchemaVersion)
+	assert.Equal(t, `1 new lines in 1 files matching "foo" or "bar"`, payload.Text)
+	assert.Equal(t, payload.Text, payload.Content)
+	assert.Equal(t, "2024-05-01T12:00:00Z", payload.Query.Timestamp)
+	assert.Equal(t, "foo()", payload.Hits[0].Lines[0].Text)
+	assert.Equal(t, grepapp.C_MARK+"foo"+grepapp.C_RST+"()", hits.Hits[0].Lines[0].Text, "hits are left highlighted")
+}
+
+func TestPostWebhookDoesNotRetryClientErrors(t *testing.T) {
+	posts := 0
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posts++
+		assert.Empty(t, r.Header.Get("X-Grepgithub-Signature"))
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer hook.Close()
+
+	args := &grepgithub.Arguments{Any: []string{"foo"}, Webhook: hook.URL, Retries: 3, RetryBase: time.Millisecond}
+	hits := &grepapp.Results{}
+	hits.AddHit("example/repo", "main.go", 3, "foo")
+	err := grepgithub.PostWebhook(context.Background(), grepapp.NewClient(), args, grepgithub.NewWebhookPayload(hits, args, time.Now()))
+	assert.EqualError(t, err, "unexpected status 404 Not Found")
+	assert.Equal(t, 1, posts)
+}